@@ -1,6 +1,7 @@
 package dgframework
 
 import (
+	"context"
 	"github.com/auttaja/dgframework/router"
 	"github.com/auttaja/discordgo"
 	"os"
@@ -137,3 +138,38 @@ func TestWaitFor(t *testing.T) {
 		t.Fatal("Did not receive correct message from WaitFor")
 	}
 }
+
+func TestWaitForTimeout(t *testing.T) {
+	if bot == nil {
+		t.Skip("Skipping, bot wasn't created.")
+	}
+
+	w := WaitForTimeout(bot.Session, discordgo.MessageCreate{}, func(i interface{}) bool {
+		return false
+	}, 10*time.Millisecond)
+
+	select {
+	case _, ok := <-w.Response:
+		if ok {
+			t.Fatal("Received an event that should have failed the check")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Response wasn't closed after the timeout")
+	}
+
+	if w.Err() != context.DeadlineExceeded {
+		t.Fatal("Err should be context.DeadlineExceeded, got", w.Err())
+	}
+
+	w = WaitFor(bot.Session, discordgo.MessageCreate{}, func(i interface{}) bool {
+		return false
+	})
+	w.Cancel()
+
+	if _, ok := <-w.Response; ok {
+		t.Fatal("Received an event after cancelling")
+	}
+	if w.Err() != ErrWaiterCancelled {
+		t.Fatal("Err should be ErrWaiterCancelled, got", w.Err())
+	}
+}
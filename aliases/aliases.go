@@ -0,0 +1,124 @@
+package aliases
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/auttaja/dgframework/router"
+)
+
+// maxDepth is the maximum amount of aliases that can expand into each other
+const maxDepth = 10
+
+var (
+	// ErrAliasLoop gets returned when aliases expand into each other endlessly
+	ErrAliasLoop = errors.New("aliases expand into each other in a loop")
+
+	// ErrTooDeep gets returned when more than maxDepth aliases expand into each other
+	ErrTooDeep = errors.New("too many aliases expand into each other")
+
+	placeholder = regexp.MustCompile(`\{(\d+)(\+?)\}`)
+)
+
+// Alias is a single alias a guild defined
+type Alias struct {
+	GuildID string `bson:"guild_id"`
+	Name    string `bson:"name"`
+	// Expansion is the command the alias stands for, it may contain the placeholders {0}, {1}, ...
+	// which get replaced by the argument given to the alias at that position,
+	// and {0+}, {1+}, ... which get replaced by all the arguments from that position on.
+	// Without placeholders the arguments are appended to the expansion
+	Expansion string `bson:"expansion"`
+}
+
+// Apply expands the alias using the arguments given to it
+func (a *Alias) Apply(args router.Args) router.Args {
+	tokens := router.ParseArgs(a.Expansion)
+	if !placeholder.MatchString(a.Expansion) {
+		return append(tokens, args...)
+	}
+
+	var expanded router.Args
+	for _, token := range tokens {
+		match := placeholder.FindStringSubmatch(token)
+		if match != nil && match[0] == token {
+			n, _ := strconv.Atoi(match[1])
+			if match[2] == "+" {
+				if n < len(args) {
+					expanded = append(expanded, args[n:]...)
+				}
+			} else if arg := args.Get(n); arg != "" {
+				expanded = append(expanded, arg)
+			}
+			continue
+		}
+
+		expanded = append(expanded, placeholder.ReplaceAllStringFunc(token, func(p string) string {
+			m := placeholder.FindStringSubmatch(p)
+			n, _ := strconv.Atoi(m[1])
+			if m[2] == "+" {
+				return args.After(n)
+			}
+			return args.Get(n)
+		}))
+	}
+	return expanded
+}
+
+// Target returns the name of the command the alias expands into
+func (a *Alias) Target() string {
+	return router.ParseArgs(a.Expansion).Get(0)
+}
+
+// expand keeps expanding args as long as it starts with one of the aliases
+func expand(aliases map[string]*Alias, args router.Args) (router.Args, error) {
+	seen := map[string]bool{}
+	for depth := 0; ; depth++ {
+		a, ok := aliases[args.Get(0)]
+		if !ok {
+			return args, nil
+		}
+		if seen[a.Name] {
+			return nil, ErrAliasLoop
+		}
+		if depth >= maxDepth {
+			return nil, ErrTooDeep
+		}
+		seen[a.Name] = true
+		args = a.Apply(args[1:])
+	}
+}
+
+// checkLoop makes sure adding the alias to the existing ones doesn't create a loop
+func checkLoop(existing map[string]*Alias, a *Alias) error {
+	aliases := make(map[string]*Alias, len(existing)+1)
+	for name, alias := range existing {
+		aliases[name] = alias
+	}
+	aliases[a.Name] = a
+
+	seen := map[string]bool{}
+	for current, ok := a, true; ok; current, ok = aliases[current.Target()] {
+		if seen[current.Name] {
+			return ErrAliasLoop
+		}
+		if len(seen) >= maxDepth {
+			return ErrTooDeep
+		}
+		seen[current.Name] = true
+	}
+	return nil
+}
+
+// resolveTarget follows the aliases to the name of the command they finally expand into
+func resolveTarget(aliases map[string]*Alias, name string) string {
+	for depth := 0; depth < maxDepth; depth++ {
+		a, ok := aliases[name]
+		if !ok {
+			return name
+		}
+		name = a.Target()
+	}
+	return name
+}
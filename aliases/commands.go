@@ -0,0 +1,115 @@
+package aliases
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+// Register makes the store the AliasResolver of the router and adds the alias commands to it.
+// Adding and removing aliases requires the Manage Server permission
+func Register(r *router.Route, s *Store) {
+	r.GuildAliases = s
+
+	manager := router.RequireGuildPermission(discordgo.PermissionManageServer)
+
+	alias := r.On("alias", s.listCommand).
+		Desc("Lists the aliases of this server").
+		Usage("alias")
+	alias.On("add", manager(s.addCommand)).
+		Desc("Adds or replaces an alias for a command, use {0}, {1}, ... for the arguments given to the alias and {0+} for all of them").
+		Usage("alias add <name> <command> [arguments]")
+	alias.On("remove", manager(s.removeCommand)).
+		Alias("delete").
+		Desc("Removes an alias").
+		Usage("alias remove <name>")
+}
+
+func (s *Store) listCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+
+	aliases, err := s.Aliases(ctx.Msg.GuildID)
+	if err != nil {
+		return err
+	}
+
+	if len(aliases) == 0 {
+		_, err = ctx.Reply("This server has no aliases")
+		return err
+	}
+
+	var lines []string
+	for _, a := range aliases {
+		lines = append(lines, fmt.Sprintf("`%s` → `%s`", a.Name, a.Expansion))
+	}
+	sort.Strings(lines)
+
+	_, err = ctx.ReplyEmbed(
+		discordgo.NewEmbed().
+			SetTitle("Aliases").
+			SetDescription(strings.Join(lines, "\n")),
+	)
+	return err
+}
+
+func (s *Store) addCommand(ctx *router.Context) error {
+	if len(ctx.Args) < 3 {
+		return router.ErrInvalidArgument
+	}
+
+	a := &Alias{
+		GuildID:   ctx.Msg.GuildID,
+		Name:      ctx.Args[1],
+		Expansion: ctx.Args.After(2),
+	}
+
+	root := ctx.Route.Root()
+	if root.Find(a.Name) != nil {
+		_, err := ctx.Reply(fmt.Sprintf("`%s` is already a command and can't be used as an alias", a.Name))
+		return err
+	}
+
+	aliases, err := s.Aliases(a.GuildID)
+	if err != nil {
+		return err
+	}
+	if target := resolveTarget(aliases, a.Target()); target != a.Name && root.Find(target) == nil {
+		_, err = ctx.Reply(fmt.Sprintf("`%s` is not a command or alias", target))
+		return err
+	}
+
+	err = s.Set(a)
+	switch err {
+	case nil:
+	case ErrAliasLoop:
+		_, err = ctx.Reply(fmt.Sprintf("`%s` would end up expanding into itself", a.Name))
+		return err
+	case ErrTooDeep:
+		_, err = ctx.Reply(fmt.Sprintf("`%s` would expand into more than %d aliases", a.Name, maxDepth))
+		return err
+	default:
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("`%s` is now an alias for `%s`", a.Name, a.Expansion))
+	return err
+}
+
+func (s *Store) removeCommand(ctx *router.Context) error {
+	if len(ctx.Args) < 2 {
+		return router.ErrInvalidArgument
+	}
+
+	err := s.Remove(ctx.Msg.GuildID, ctx.Args[1])
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Removed the alias `%s`", ctx.Args[1]))
+	return err
+}
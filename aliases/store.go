@@ -0,0 +1,108 @@
+package aliases
+
+import (
+	"context"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store is a router.AliasResolver that keeps the aliases in MongoDB and caches them per guild
+type Store struct {
+	collection *mongo.Collection
+	cache      *router.TTLCache
+}
+
+// NewStore returns a new Store
+// collection : the collection to keep the aliases in
+// ttl        : how long the aliases of a guild are cached before they get fetched again
+func NewStore(collection *mongo.Collection, ttl time.Duration) *Store {
+	return &Store{
+		collection: collection,
+		cache:      router.NewTTLCache(ttl),
+	}
+}
+
+// Expand expands the alias args starts with, following aliases that expand into other aliases
+func (s *Store) Expand(guildID string, args router.Args) (router.Args, error) {
+	aliases, err := s.Aliases(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return expand(aliases, args)
+}
+
+// Aliases returns all the aliases of a guild by name, from the cache if possible
+func (s *Store) Aliases(guildID string) (map[string]*Alias, error) {
+	aliases, err := s.cache.Get(guildID, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cursor, err := s.collection.Find(ctx, bson.M{"guild_id": guildID})
+		if err != nil {
+			return nil, err
+		}
+
+		var list []*Alias
+		err = cursor.All(ctx, &list)
+		if err != nil {
+			return nil, err
+		}
+
+		aliases := make(map[string]*Alias, len(list))
+		for _, a := range list {
+			aliases[a.Name] = a
+		}
+		return aliases, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aliases.(map[string]*Alias), nil
+}
+
+// Set creates or replaces an alias, it returns ErrAliasLoop or ErrTooDeep if the alias
+// would end up expanding into itself or too many other aliases
+func (s *Store) Set(a *Alias) error {
+	aliases, err := s.Aliases(a.GuildID)
+	if err != nil {
+		return err
+	}
+
+	err = checkLoop(aliases, a)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"guild_id": a.GuildID, "name": a.Name}
+	_, err = s.collection.ReplaceOne(ctx, filter, a, options.Replace().SetUpsert(true))
+	s.Invalidate(a.GuildID)
+	return err
+}
+
+// Remove removes an alias, it returns mongo.ErrNoDocuments if the guild has no alias with the name
+func (s *Store) Remove(guildID, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.collection.DeleteOne(ctx, bson.M{"guild_id": guildID, "name": name})
+	s.Invalidate(guildID)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Invalidate drops the cached aliases of a guild
+func (s *Store) Invalidate(guildID string) {
+	s.cache.Invalidate(guildID)
+}
@@ -0,0 +1,230 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/auttaja/discordgo"
+)
+
+// StopReason tells why a collector stopped collecting
+type StopReason int
+
+// The reasons a collector can stop for
+const (
+	// Collecting has not stopped yet
+	NotStopped StopReason = iota
+	// The maximum amount of events was collected
+	StopMax
+	// The total timeout passed
+	StopTimeout
+	// No event was collected within the idle timeout
+	StopIdle
+	// Stop was called, the context was done or the dispatcher of the session was removed
+	StopCancelled
+)
+
+// CollectorOptions configure when a collector stops, a zero value means no limit.
+// A collector without any limits only stops when Stop is called or its context is done
+type CollectorOptions struct {
+	// Max is the maximum amount of events to collect
+	Max int
+	// Timeout is how long to collect for in total
+	Timeout time.Duration
+	// Idle is how long to wait for the next event before stopping
+	Idle time.Duration
+}
+
+// collector gathers the events accepted by its check until one of its limits is reached
+type collector struct {
+	opts   CollectorOptions
+	accept func(interface{}) bool
+	remove func()
+
+	mu        sync.Mutex
+	collected []interface{}
+	reason    StopReason
+	done      chan struct{}
+	timeout   *time.Timer
+	idle      *time.Timer
+}
+
+func newCollector(ctx context.Context, s *discordgo.Session, event interface{}, filter Filter, opts CollectorOptions, accept func(interface{}) bool) *collector {
+	c := &collector{
+		opts:   opts,
+		accept: accept,
+		done:   make(chan struct{}),
+	}
+
+	// the listener can't stop the collector before remove is set, as it needs the lock
+	d := For(s)
+	c.mu.Lock()
+	c.remove = d.Add(event, filter, c.listen)
+	if opts.Timeout > 0 {
+		c.timeout = time.AfterFunc(opts.Timeout, func() { c.stop(StopTimeout) })
+	}
+	if opts.Idle > 0 {
+		c.idle = time.AfterFunc(opts.Idle, func() { c.stop(StopIdle) })
+	}
+	c.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.stop(StopCancelled)
+		case <-d.Done():
+			c.stop(StopCancelled)
+		case <-c.done:
+		}
+	}()
+
+	return c
+}
+
+func (c *collector) listen(event interface{}) {
+	if !c.accept(event) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reason != NotStopped {
+		return
+	}
+
+	c.collected = append(c.collected, event)
+	if c.opts.Max > 0 && len(c.collected) >= c.opts.Max {
+		c.stopLocked(StopMax)
+		return
+	}
+	if c.idle != nil {
+		c.idle.Reset(c.opts.Idle)
+	}
+}
+
+func (c *collector) stop(reason StopReason) {
+	c.mu.Lock()
+	c.stopLocked(reason)
+	c.mu.Unlock()
+}
+
+// stopLocked stops collecting, c.mu must be held. Only the first call does anything
+func (c *collector) stopLocked(reason StopReason) {
+	if c.reason != NotStopped {
+		return
+	}
+
+	c.reason = reason
+	c.remove()
+	if c.timeout != nil {
+		c.timeout.Stop()
+	}
+	if c.idle != nil {
+		c.idle.Stop()
+	}
+	close(c.done)
+}
+
+// snapshot returns the events collected so far
+func (c *collector) snapshot() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]interface{}(nil), c.collected...)
+}
+
+func (c *collector) stopReason() StopReason {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+// MessageCollector collects the messages sent in a channel
+type MessageCollector struct {
+	c *collector
+}
+
+// CollectMessages starts collecting the messages sent in the channel for which check returns true,
+// a nil check accepts every message. For example the first 10 answers to a trivia question
+func CollectMessages(ctx context.Context, s *discordgo.Session, channelID string, opts CollectorOptions, check func(*discordgo.Message) bool) *MessageCollector {
+	return &MessageCollector{c: newCollector(ctx, s, discordgo.MessageCreate{}, Filter{ChannelID: channelID}, opts, func(event interface{}) bool {
+		return check == nil || check(event.(*discordgo.MessageCreate).Message)
+	})}
+}
+
+// Wait waits until the collector stops and returns the collected messages
+func (m *MessageCollector) Wait() []*discordgo.Message {
+	<-m.c.done
+	return m.Collected()
+}
+
+// Collected returns the messages collected so far
+func (m *MessageCollector) Collected() []*discordgo.Message {
+	events := m.c.snapshot()
+	messages := make([]*discordgo.Message, len(events))
+	for i, e := range events {
+		messages[i] = e.(*discordgo.MessageCreate).Message
+	}
+	return messages
+}
+
+// Stop stops collecting
+func (m *MessageCollector) Stop() {
+	m.c.stop(StopCancelled)
+}
+
+// Done returns a channel that is closed when the collector stops
+func (m *MessageCollector) Done() <-chan struct{} {
+	return m.c.done
+}
+
+// Reason returns why the collector stopped, or NotStopped if it is still collecting
+func (m *MessageCollector) Reason() StopReason {
+	return m.c.stopReason()
+}
+
+// ReactionCollector collects the reactions added to a message
+type ReactionCollector struct {
+	c *collector
+}
+
+// CollectReactions starts collecting the reactions added to the message for which check returns true,
+// a nil check accepts every reaction. For example all entries of a giveaway for 24 hours
+func CollectReactions(ctx context.Context, s *discordgo.Session, message *discordgo.Message, opts CollectorOptions, check func(*discordgo.MessageReaction) bool) *ReactionCollector {
+	return &ReactionCollector{c: newCollector(ctx, s, discordgo.MessageReactionAdd{}, Filter{ChannelID: message.ChannelID}, opts, func(event interface{}) bool {
+		r := event.(*discordgo.MessageReactionAdd).MessageReaction
+		return r.MessageID == message.ID && (check == nil || check(r))
+	})}
+}
+
+// Wait waits until the collector stops and returns the collected reactions
+func (r *ReactionCollector) Wait() []*discordgo.MessageReaction {
+	<-r.c.done
+	return r.Collected()
+}
+
+// Collected returns the reactions collected so far
+func (r *ReactionCollector) Collected() []*discordgo.MessageReaction {
+	events := r.c.snapshot()
+	reactions := make([]*discordgo.MessageReaction, len(events))
+	for i, e := range events {
+		reactions[i] = e.(*discordgo.MessageReactionAdd).MessageReaction
+	}
+	return reactions
+}
+
+// Stop stops collecting
+func (r *ReactionCollector) Stop() {
+	r.c.stop(StopCancelled)
+}
+
+// Done returns a channel that is closed when the collector stops
+func (r *ReactionCollector) Done() <-chan struct{} {
+	return r.c.done
+}
+
+// Reason returns why the collector stopped, or NotStopped if it is still collecting
+func (r *ReactionCollector) Reason() StopReason {
+	return r.c.stopReason()
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/auttaja/discordgo"
+)
+
+func TestCollectMessagesMax(t *testing.T) {
+	s := &discordgo.Session{}
+	c := CollectMessages(context.Background(), s, "1", CollectorOptions{Max: 2}, func(m *discordgo.Message) bool {
+		return m.Author.ID != "bot"
+	})
+
+	For(s).Handle(s, messageCreate("1", "bot"))
+	For(s).Handle(s, messageCreate("2", "user"))
+	For(s).Handle(s, messageCreate("1", "user"))
+	For(s).Handle(s, messageCreate("1", "user"))
+	For(s).Handle(s, messageCreate("1", "user"))
+
+	messages := c.Wait()
+	if len(messages) != 2 || c.Reason() != StopMax {
+		t.Fatalf("collected %d messages and stopped with reason %d", len(messages), c.Reason())
+	}
+	if For(s).Len() != 0 {
+		t.Fatal("collector did not remove its listener")
+	}
+}
+
+func TestCollectReactionsIdle(t *testing.T) {
+	s := &discordgo.Session{}
+	m := &discordgo.Message{ID: "10", ChannelID: "1"}
+	c := CollectReactions(context.Background(), s, m, CollectorOptions{Idle: 20 * time.Millisecond, Timeout: time.Second}, nil)
+
+	For(s).Handle(s, &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{MessageID: "10", ChannelID: "1", UserID: "2"}})
+	For(s).Handle(s, &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{MessageID: "11", ChannelID: "1", UserID: "2"}})
+
+	select {
+	case <-c.Done():
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("collector did not stop after the idle timeout")
+	}
+
+	if len(c.Collected()) != 1 || c.Reason() != StopIdle {
+		t.Fatalf("collected %d reactions and stopped with reason %d", len(c.Collected()), c.Reason())
+	}
+}
+
+func TestRemoveStopsCollectors(t *testing.T) {
+	s := &discordgo.Session{}
+	c := CollectMessages(context.Background(), s, "1", CollectorOptions{}, nil)
+	d := For(s)
+
+	Remove(s)
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the collector kept collecting after the dispatcher was removed")
+	}
+	if c.Reason() != StopCancelled {
+		t.Fatalf("expected StopCancelled, got %d", c.Reason())
+	}
+	if d.Len() != 0 {
+		t.Fatalf("expected the listeners to be removed, %d are left", d.Len())
+	}
+	if For(s) == d {
+		t.Fatal("expected a new dispatcher after removing the old one")
+	}
+}
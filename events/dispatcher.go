@@ -0,0 +1,215 @@
+// Package events feeds gateway events to short lived listeners, like waiters and prompts, through a single
+// handler per session. Listeners are indexed by event type, channel and user, so the cost of an event
+// doesn't grow with the amount of listeners waiting for other events, channels or users
+package events
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/auttaja/discordgo"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[*discordgo.Session]*Dispatcher)
+)
+
+// Filter limits the events a listener receives to a channel and a user, an empty ID matches any.
+// Events without a channel or user, like most non message events, only reach listeners without that filter
+type Filter struct {
+	ChannelID string
+	UserID    string
+}
+
+// Listener gets called with every matching event until it is removed
+type Listener func(event interface{})
+
+// key is where listeners are indexed
+type key struct {
+	eventType reflect.Type
+	Filter
+}
+
+type listener struct {
+	fn Listener
+}
+
+// Dispatcher feeds the events of a session to its listeners
+type Dispatcher struct {
+	mu        sync.RWMutex
+	listeners map[key]map[*listener]struct{}
+
+	// removeHandler removes Handle from the session, when the dispatcher was added by For
+	removeHandler func()
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// New returns a new Dispatcher, its Handle method needs to be added to a session as handler.
+// Use For to get the dispatcher of a session instead, unless you manage the handler yourself
+func New() *Dispatcher {
+	return &Dispatcher{
+		listeners: make(map[key]map[*listener]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// For returns the dispatcher of the session, adding it as handler the first time it is asked for
+func For(s *discordgo.Session) *Dispatcher {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	d, ok := registry[s]
+	if !ok {
+		d = New()
+		d.removeHandler = s.AddHandler(d.Handle)
+		registry[s] = d
+	}
+	return d
+}
+
+// Remove removes the dispatcher of the session and its handler, and closes it so the waiters, collectors and
+// prompts still listening on it stop. Call it when the session is closed for good, the framework does in Bot.Close
+func Remove(s *discordgo.Session) {
+	registryMu.Lock()
+	d, ok := registry[s]
+	delete(registry, s)
+	registryMu.Unlock()
+
+	if ok {
+		d.removeHandler()
+		d.Close()
+	}
+}
+
+// Close removes all listeners and closes the channel returned by Done, so everything listening stops waiting
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		d.mu.Lock()
+		d.listeners = make(map[key]map[*listener]struct{})
+		d.mu.Unlock()
+		close(d.done)
+	})
+}
+
+// Done returns a channel that is closed when the dispatcher is closed, listeners should stop waiting when it is
+func (d *Dispatcher) Done() <-chan struct{} {
+	return d.done
+}
+
+// Add adds a listener for events of the same type as event, which can be given as value or pointer,
+// like discordgo.MessageCreate{} or &discordgo.MessageCreate{}. It returns a function that removes the listener
+func (d *Dispatcher) Add(event interface{}, filter Filter, fn Listener) (remove func()) {
+	t := reflect.TypeOf(event)
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PtrTo(t)
+	}
+
+	k := key{eventType: t, Filter: filter}
+	l := &listener{fn: fn}
+
+	d.mu.Lock()
+	set, ok := d.listeners[k]
+	if !ok {
+		set = make(map[*listener]struct{})
+		d.listeners[k] = set
+	}
+	set[l] = struct{}{}
+	d.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			if set, ok := d.listeners[k]; ok {
+				delete(set, l)
+				if len(set) == 0 {
+					delete(d.listeners, k)
+				}
+			}
+			d.mu.Unlock()
+		})
+	}
+}
+
+// Len returns the amount of listeners
+func (d *Dispatcher) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	n := 0
+	for _, set := range d.listeners {
+		n += len(set)
+	}
+	return n
+}
+
+// Handle feeds an event to the listeners waiting for it. The listeners are called outside of the lock,
+// so they can remove themselves or add other listeners
+func (d *Dispatcher) Handle(_ *discordgo.Session, event interface{}) {
+	t := reflect.TypeOf(event)
+	channelID, userID := eventFilter(event)
+
+	d.mu.RLock()
+	if len(d.listeners) == 0 {
+		d.mu.RUnlock()
+		return
+	}
+
+	var matched []*listener
+	matched = d.collect(matched, key{t, Filter{}})
+	if channelID != "" {
+		matched = d.collect(matched, key{t, Filter{ChannelID: channelID}})
+	}
+	if userID != "" {
+		matched = d.collect(matched, key{t, Filter{UserID: userID}})
+	}
+	if channelID != "" && userID != "" {
+		matched = d.collect(matched, key{t, Filter{ChannelID: channelID, UserID: userID}})
+	}
+	d.mu.RUnlock()
+
+	for _, l := range matched {
+		l.fn(event)
+	}
+}
+
+// collect appends the listeners with the key to matched, d.mu must be held
+func (d *Dispatcher) collect(matched []*listener, k key) []*listener {
+	for l := range d.listeners[k] {
+		matched = append(matched, l)
+	}
+	return matched
+}
+
+// eventFilter returns the channel and user of an event, if it has them
+func eventFilter(event interface{}) (channelID, userID string) {
+	switch e := event.(type) {
+	case *discordgo.MessageCreate:
+		return messageFilter(e.Message)
+	case *discordgo.MessageUpdate:
+		return messageFilter(e.Message)
+	case *discordgo.MessageDelete:
+		return messageFilter(e.Message)
+	case *discordgo.MessageReactionAdd:
+		return e.ChannelID, e.UserID
+	case *discordgo.MessageReactionRemove:
+		return e.ChannelID, e.UserID
+	case *discordgo.MessageReactionRemoveAll:
+		return e.ChannelID, ""
+	case *discordgo.TypingStart:
+		return e.ChannelID, e.UserID
+	}
+	return "", ""
+}
+
+func messageFilter(m *discordgo.Message) (channelID, userID string) {
+	if m == nil {
+		return "", ""
+	}
+	if m.Author != nil {
+		userID = m.Author.ID
+	}
+	return m.ChannelID, userID
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/auttaja/discordgo"
+)
+
+func messageCreate(channelID, userID string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: channelID,
+		Author:    &discordgo.User{ID: userID},
+	}}
+}
+
+func TestDispatcherFilters(t *testing.T) {
+	d := New()
+
+	var any, channel, user, both, reactions int
+	d.Add(discordgo.MessageCreate{}, Filter{}, func(interface{}) { any++ })
+	d.Add(&discordgo.MessageCreate{}, Filter{ChannelID: "1"}, func(interface{}) { channel++ })
+	d.Add(discordgo.MessageCreate{}, Filter{UserID: "2"}, func(interface{}) { user++ })
+	d.Add(discordgo.MessageCreate{}, Filter{ChannelID: "1", UserID: "2"}, func(interface{}) { both++ })
+	d.Add(discordgo.MessageReactionAdd{}, Filter{}, func(interface{}) { reactions++ })
+
+	d.Handle(nil, messageCreate("1", "2"))
+	d.Handle(nil, messageCreate("1", "3"))
+	d.Handle(nil, messageCreate("4", "2"))
+	d.Handle(nil, messageCreate("4", "3"))
+
+	if any != 4 || channel != 2 || user != 2 || both != 1 || reactions != 0 {
+		t.Fatalf("wrong amount of calls: any %d, channel %d, user %d, both %d, reactions %d", any, channel, user, both, reactions)
+	}
+}
+
+func TestDispatcherRemove(t *testing.T) {
+	d := New()
+
+	calls := 0
+	var remove func()
+	remove = d.Add(discordgo.MessageCreate{}, Filter{ChannelID: "1"}, func(interface{}) {
+		calls++
+		remove()
+	})
+
+	d.Handle(nil, messageCreate("1", "2"))
+	d.Handle(nil, messageCreate("1", "2"))
+	remove()
+
+	if calls != 1 {
+		t.Fatalf("listener was called %d times after removing itself", calls)
+	}
+	if d.Len() != 0 {
+		t.Fatalf("dispatcher still has %d listeners", d.Len())
+	}
+}
+
+// BenchmarkHandle measures the cost of an event while listeners wait for other channels,
+// which should stay the same no matter how many there are
+func BenchmarkHandle(b *testing.B) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		b.Run(fmt.Sprintf("%d listeners", n), func(b *testing.B) {
+			d := New()
+			for i := 0; i < n; i++ {
+				d.Add(discordgo.MessageCreate{}, Filter{ChannelID: strconv.Itoa(i), UserID: "1"}, func(interface{}) {})
+			}
+			d.Add(discordgo.MessageCreate{}, Filter{ChannelID: "target", UserID: "1"}, func(interface{}) {})
+
+			event := messageCreate("target", "1")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				d.Handle(nil, event)
+			}
+		})
+	}
+}
+
+// BenchmarkHandleUnmatched measures the cost of an event nobody waits for
+func BenchmarkHandleUnmatched(b *testing.B) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		b.Run(fmt.Sprintf("%d listeners", n), func(b *testing.B) {
+			d := New()
+			for i := 0; i < n; i++ {
+				d.Add(discordgo.MessageReactionAdd{}, Filter{ChannelID: strconv.Itoa(i)}, func(interface{}) {})
+			}
+
+			event := messageCreate("target", "1")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				d.Handle(nil, event)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package dgframework
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,7 +12,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/auttaja/dgframework/aliases"
+	"github.com/auttaja/dgframework/events"
 	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/dgframework/tags"
+	"github.com/auttaja/dgframework/timeparse"
+	"github.com/auttaja/dgframework/toggles"
 	"github.com/auttaja/dgframework/utils"
 	"github.com/auttaja/dgframework/x/discordrolemanager"
 	"github.com/auttaja/discordgo"
@@ -22,13 +28,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrNoDatabase gets returned when building a bot that uses a feature which needs the database,
+// without setting the DB session and name
+var ErrNoDatabase = errors.New("this feature needs a DB session and DB name to be set")
+
 // Bot represents a Discord bot
 type Bot struct {
 	Session       *discordgo.Session
 	DB            *mongo.Client
 	Router        *router.Route
 	Enforcer      *casbin.Enforcer
+	TimeZones     *timeparse.ZoneStore
 	snowflakeNode *snowflake.Node
+	dbName        string
+	closers       []func()
 }
 
 // BotBuilder is a convenience struct for making the Bot object
@@ -39,7 +52,17 @@ type BotBuilder struct {
 	shardCount        int
 	pluginLocation    string
 	dbSession         *mongo.Client
+	dbName            string
 	useStatefulEmbeds bool
+	persistEmbeds     bool
+	useToggles        bool
+	useAliases        bool
+	useTags           bool
+	useTimeZones      bool
+	useHelpCommand    bool
+	usePipes          bool
+	stateCacheSize    int
+	stateCacheTTL     time.Duration
 	startBot          bool
 	casbinDBURL       string
 	stateURL          string
@@ -81,12 +104,76 @@ func (b *BotBuilder) SetDBSession(session *mongo.Client) *BotBuilder {
 	return b
 }
 
+// SetDBName sets the name of the database that the framework's own features store their data in
+func (b *BotBuilder) SetDBName(name string) *BotBuilder {
+	b.dbName = name
+	return b
+}
+
+// UseCommandToggles adds the enable and disable commands to the router, which allow server admins
+// to disable commands or categories in their server or specific channels. Needs the DB session and name to be set
+func (b *BotBuilder) UseCommandToggles() *BotBuilder {
+	b.useToggles = true
+	return b
+}
+
+// UseGuildAliases adds the alias commands to the router, which allow server admins to define
+// their own aliases for commands. Needs the DB session and name to be set
+func (b *BotBuilder) UseGuildAliases() *BotBuilder {
+	b.useAliases = true
+	return b
+}
+
+// UseTags adds the tag commands to the router, which allow guilds to store text that can be shown
+// using the name of the tag as if it were a command. Needs the DB session and name to be set
+func (b *BotBuilder) UseTags() *BotBuilder {
+	b.useTags = true
+	return b
+}
+
+// UseTimeZones adds the timezone command to the router, which allows users and guilds to set the time zone
+// that times like "tomorrow 9am" are parsed in. The store is available as Bot.TimeZones. Needs the DB session and name to be set
+func (b *BotBuilder) UseTimeZones() *BotBuilder {
+	b.useTimeZones = true
+	return b
+}
+
+// UsePipes enables piping the output of a command into another command, like "-search foo | -translate de"
+func (b *BotBuilder) UsePipes() *BotBuilder {
+	b.usePipes = true
+	return b
+}
+
+// UseStateCache makes the Context getters cache the guilds, channels and members they had to fetch
+// from the REST API because they were missing from the state
+// size : the maximum amount of objects to cache
+// ttl  : how long a fetched object stays cached
+func (b *BotBuilder) UseStateCache(size int, ttl time.Duration) *BotBuilder {
+	b.stateCacheSize = size
+	b.stateCacheTTL = ttl
+	return b
+}
+
+// UseHelpCommand adds the built-in help command to the router
+func (b *BotBuilder) UseHelpCommand() *BotBuilder {
+	b.useHelpCommand = true
+	return b
+}
+
 // UseStatefulEmbeds will make the builder also add the handlers needed for the statefulembeds from utils to work
 func (b *BotBuilder) UseStatefulEmbeds() *BotBuilder {
 	b.useStatefulEmbeds = true
 	return b
 }
 
+// UsePersistentEmbeds is like UseStatefulEmbeds, but also saves the embed sessions to the database so
+// their reactions keep working after a restart. Needs the DB session and name to be set
+func (b *BotBuilder) UsePersistentEmbeds() *BotBuilder {
+	b.useStatefulEmbeds = true
+	b.persistEmbeds = true
+	return b
+}
+
 // SetPluginLocation sets the location with the plugins and will make the builder also load the plugins
 func (b *BotBuilder) SetPluginLocation(location string) *BotBuilder {
 	b.pluginLocation = location
@@ -124,6 +211,8 @@ func (b *BotBuilder) Build() (bot *Bot, err error) {
 	if err != nil {
 		return
 	}
+	bot.dbName = b.dbName
+	bot.Router.Pipes = b.usePipes
 
 	if b.stateURL != "" {
 		log.Println("Using remote state")
@@ -151,9 +240,72 @@ func (b *BotBuilder) Build() (bot *Bot, err error) {
 		}
 	}
 
+	// prompts of running commands stop waiting when the bot closes
+	baseContext, cancel := context.WithCancel(context.Background())
+	bot.Router.BaseContext = baseContext
+	bot.closers = append(bot.closers, cancel)
+
+	if b.stateCacheTTL > 0 {
+		negativeTTL := 30 * time.Second
+		if b.stateCacheTTL < negativeTTL {
+			negativeTTL = b.stateCacheTTL
+		}
+		bot.Router.StateCache = router.NewStateCache(b.stateCacheSize, b.stateCacheTTL, negativeTTL)
+		bot.Router.StateCache.AddHandlers(bot.Session)
+	}
+
+	if b.useToggles {
+		if b.dbSession == nil || b.dbName == "" {
+			return nil, ErrNoDatabase
+		}
+		toggles.Register(bot.Router, toggles.NewStore(bot.Database().Collection("command_toggles"), 5*time.Minute))
+	}
+
+	if b.useAliases {
+		if b.dbSession == nil || b.dbName == "" {
+			return nil, ErrNoDatabase
+		}
+		aliases.Register(bot.Router, aliases.NewStore(bot.Database().Collection("command_aliases"), 5*time.Minute))
+	}
+
+	if b.useTags {
+		if b.dbSession == nil || b.dbName == "" {
+			return nil, ErrNoDatabase
+		}
+		tags.Register(bot.Router, tags.NewStore(bot.Database().Collection("tags"), 5*time.Minute))
+	}
+
+	if b.useTimeZones {
+		if b.dbSession == nil || b.dbName == "" {
+			return nil, ErrNoDatabase
+		}
+		bot.TimeZones = timeparse.NewZoneStore(bot.Database().Collection("time_zones"), 5*time.Minute)
+		timeparse.Register(bot.Router, bot.TimeZones)
+	}
+
+	if b.useHelpCommand {
+		bot.Router.On("help", router.HelpHandler).
+			Desc("Lists the available commands, or shows information about the given command").
+			Usage("help [command]")
+	}
+
+	if b.persistEmbeds {
+		if b.dbSession == nil || b.dbName == "" {
+			return nil, ErrNoDatabase
+		}
+		store := utils.NewMongoSessionStore(bot.Database().Collection("embed_sessions"))
+		if err := store.EnsureTTLIndex(utils.SessionRetention); err != nil {
+			log.Println("could not create the TTL index of the embed sessions", err)
+		}
+		utils.SetSessionStore(store)
+		utils.SetMongoClient(b.dbSession)
+	}
+
 	if b.useStatefulEmbeds {
 		bot.Session.AddHandler(utils.StatefulMessageDelete)
 		bot.Session.AddHandler(utils.StatefulReactionHandler)
+		bot.Session.AddHandler(utils.StatefulReactionRemoveHandler)
+		bot.closers = append(bot.closers, utils.StartJanitor(time.Minute))
 	}
 
 	if b.pluginLocation != "" {
@@ -268,6 +420,22 @@ func (b *Bot) processGuildMembersChunk(s *discordgo.Session, c *discordgo.GuildM
 	fmt.Printf("Processing %d members for %s\n", len(c.Members), c.GuildID)
 }
 
+// Close stops everything the bot runs in the background, like the stateful embed janitor and the waiters, and closes the discord session
+func (b *Bot) Close() error {
+	for _, c := range b.closers {
+		c()
+	}
+	b.closers = nil
+	events.Remove(b.Session)
+
+	return b.Session.Close()
+}
+
+// Database returns the database the framework's own features store their data in
+func (b *Bot) Database() *mongo.Database {
+	return b.DB.Database(b.dbName)
+}
+
 // GenerateSnowflake generates an internal snowflake that can be used to produce unique IDs
 func (b *Bot) GenerateSnowflake() snowflake.ID {
 	return b.snowflakeNode.Generate()
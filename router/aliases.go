@@ -0,0 +1,8 @@
+package router
+
+// AliasResolver expands the aliases a guild defined itself into the command they stand for
+type AliasResolver interface {
+	// Expand returns args with the alias it starts with replaced by the command it stands for,
+	// or args unchanged if it doesn't start with an alias of the guild
+	Expand(guildID string, args Args) (Args, error)
+}
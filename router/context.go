@@ -1,7 +1,9 @@
 package router
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/auttaja/discordgo"
@@ -18,6 +20,24 @@ type Context struct {
 	// List of arguments supplied with the command
 	Args Args
 
+	// Input is the output of the previous command when this command is part of a pipeline,
+	// it is also the last of the Args
+	Input string
+
+	// AllowedMentions lists the mentions that Reply and the SendMessage functions may send,
+	// all others get neutralized. When nil nobody gets mentioned
+	AllowedMentions *AllowedMentions
+
+	// MaxReplyMessages is the maximum amount of messages that content too long for a single message
+	// gets split into, when more would be needed it gets uploaded as a file instead. 0 means no maximum
+	MaxReplyMessages int
+
+	// ctx is the context.Context of the command, see Context and WithContext
+	ctx context.Context
+
+	// capture collects the output of the command when it gets piped into another command
+	capture *strings.Builder
+
 	// Vars that can be optionally set using the Set and Get functions
 	vmu  sync.RWMutex
 	Vars map[string]interface{}
@@ -38,14 +58,26 @@ func (c *Context) Get(key string) interface{} {
 	return nil
 }
 
-// Reply replies to the sender with the given message
+// Reply replies to the sender with the given message, mentions that are not in the AllowedMentions
+// of the context get neutralized and messages that are too long get split
 func (c *Context) Reply(args ...interface{}) (*discordgo.Message, error) {
-	return c.Ses.ChannelMessageSend(c.Msg.ChannelID, fmt.Sprint(args...))
+	return c.ReplyAllowing(c.AllowedMentions, args...)
+}
+
+// ReplyAllowing replies to the sender with the given message, only allowing the given mentions
+func (c *Context) ReplyAllowing(allowed *AllowedMentions, args ...interface{}) (*discordgo.Message, error) {
+	if c.Piped() {
+		return c.write(fmt.Sprint(args...)), nil
+	}
+	return c.sendSplit(c.sanitize(fmt.Sprint(args...), allowed), nil, nil)
 }
 
-// ReplyEmbed replies to the sender with an embed
+// ReplyEmbed replies to the sender with an embed, embeds exceeding Discord's limits get split over multiple messages
 func (c *Context) ReplyEmbed(embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
-	return c.Channel.SendMessage("", embed, nil)
+	if c.Piped() {
+		return c.write(embedText(embed)), nil
+	}
+	return c.sendSplit("", embed, nil)
 }
 
 // Guild returns the guild the context originated from if it did, else an error
@@ -58,8 +90,20 @@ func (c *Context) Author() *discordgo.User {
 	return c.Msg.Author
 }
 
-// GetGuild retrieves a guild from the state or restapi
+// stateCache returns the StateCache of the router, if it has one
+func (c *Context) stateCache() *StateCache {
+	if c.Route == nil {
+		return nil
+	}
+	return c.Route.Root().StateCache
+}
+
+// GetGuild retrieves a guild from the state, the StateCache of the router if it has one, or restapi
 func (c *Context) GetGuild(guildID string) (*discordgo.Guild, error) {
+	if cache := c.stateCache(); cache != nil {
+		return cache.Guild(c.Ses, guildID)
+	}
+
 	g, err := c.Ses.State.Guild(guildID)
 	if err != nil {
 		g, err = c.Ses.Guild(guildID)
@@ -67,8 +111,12 @@ func (c *Context) GetGuild(guildID string) (*discordgo.Guild, error) {
 	return g, err
 }
 
-// GetChannel retrieves a channel from the state or restapi
+// GetChannel retrieves a channel from the state, the StateCache of the router if it has one, or restapi
 func (c *Context) GetChannel(channelID string) (*discordgo.Channel, error) {
+	if cache := c.stateCache(); cache != nil {
+		return cache.Channel(c.Ses, channelID)
+	}
+
 	ch, err := c.Ses.State.Channel(channelID)
 	if err != nil {
 		ch, err = c.Ses.Channel(channelID)
@@ -76,8 +124,12 @@ func (c *Context) GetChannel(channelID string) (*discordgo.Channel, error) {
 	return ch, err
 }
 
-// GetMember retrieves a member from the state or restapi
+// GetMember retrieves a member from the state, the StateCache of the router if it has one, or restapi
 func (c *Context) GetMember(guildID, userID string) (*discordgo.Member, error) {
+	if cache := c.stateCache(); cache != nil {
+		return cache.Member(c.Ses, guildID, userID)
+	}
+
 	m, err := c.Ses.State.Member(guildID, userID)
 	if err != nil {
 		m, err = c.Ses.GuildMember(guildID, userID)
@@ -85,14 +137,44 @@ func (c *Context) GetMember(guildID, userID string) (*discordgo.Member, error) {
 	return m, err
 }
 
-// SendMessage sends a message to the channel
+// AuthorHasPermission checks whether the author of the message has the given permission(s) in the context channel.
+// Administrators and the owner of the guild are considered to have every permission
+func (c *Context) AuthorHasPermission(permission discordgo.PermissionOffset) (bool, error) {
+	ch, err := c.GetChannel(c.Msg.ChannelID)
+	if err != nil {
+		return false, err
+	}
+	m, err := c.GetMember(c.Msg.GuildID, c.Msg.Author.ID)
+	if err != nil {
+		return false, err
+	}
+
+	perms, err := ch.PermissionsFor(m)
+	if err != nil {
+		return false, err
+	}
+	return perms.Has(permission), nil
+}
+
+// SendMessage sends a message to the channel, mentions in the content that are not in the AllowedMentions
+// of the context get neutralized. Content and embeds exceeding Discord's limits get split over multiple messages
 func (c Context) SendMessage(content string, embed *discordgo.MessageEmbed, files []*discordgo.File) (message *discordgo.Message, err error) {
-	return c.Channel.SendMessage(content, embed, files)
+	if c.Piped() {
+		return c.write(strings.TrimSpace(content + "\n" + embedText(embed))), nil
+	}
+	return c.sendSplit(c.sanitize(content, c.AllowedMentions), embed, files)
 }
 
-// SendMessageComplex sends a message to the channel
+// SendMessageComplex sends a message to the channel, mentions in the content that are not in the AllowedMentions
+// of the context get neutralized
 func (c Context) SendMessageComplex(data *discordgo.MessageSend) (message *discordgo.Message, err error) {
-	return c.Channel.SendMessageComplex(data)
+	if c.Piped() {
+		return c.write(strings.TrimSpace(data.Content + "\n" + embedText(data.Embed))), nil
+	}
+	// the caller's data is left alone, it may be sent again with other allowed mentions
+	send := *data
+	send.Content = c.sanitize(data.Content, c.AllowedMentions)
+	return c.Channel.SendMessageComplex(&send)
 }
 
 // EditMessage edits an existing message, replacing it entirely with
@@ -16,6 +16,9 @@ var (
 	// ErrCouldNotFindRoute gets returned when a route could not be found
 	ErrCouldNotFindRoute = errors.New("could not find route")
 
+	// ErrCommandDisabled gets returned when a route was found, but it has been disabled in the guild or channel
+	ErrCommandDisabled = errors.New("command has been disabled")
+
 	// ErrRouteAlreadyExists gets returned when a route gets added that already exists
 	ErrRouteAlreadyExists = errors.New("route already exists")
 
@@ -39,6 +42,13 @@ var (
 	// a resource that doesn't exist and the bot does not handle the return message itself
 	ErrNotFound = errors.New("the requested object wasn't found")
 
+	// ErrMembersNotCached gets returned when a member is looked up by name in a guild whose members
+	// haven't been requested from the gateway, so the member may exist without being found
+	ErrMembersNotCached = errors.New("the members of the guild haven't been cached")
+
+	// ErrPipelineTooLong gets returned when more commands are piped into each other than allowed
+	ErrPipelineTooLong = errors.New("too many commands have been piped into each other")
+
 	// ErrNotImplemented gets thrown when a function or command gets called that hasn't been implemented yet
 	ErrNotImplemented = errors.New("this hasn't been implemented yet")
 )
@@ -49,6 +59,9 @@ func HandleError(ctx *Context, err error) {
 		return
 	}
 
+	// errors always get shown, even when the output of the command would have been piped
+	ctx.capture = nil
+
 	switch err.(type) {
 	case *discordgo.RESTError:
 		if err.(*discordgo.RESTError).Response.StatusCode == 403 {
@@ -75,6 +88,10 @@ func HandleError(ctx *Context, err error) {
 		errString = "This command cannot be ran in a Guild"
 	case ErrNotFound:
 		errString = "The resource or object the command needed does not exist"
+	case ErrMembersNotCached:
+		errString = "I couldn't find that member by name, please mention them or use their ID instead"
+	case ErrPipelineTooLong:
+		errString = fmt.Sprintf("You can only pipe up to %d commands into each other", maxPipeStages)
 	case ErrNotImplemented:
 		errString = "This hasn't been implemented yet, please try again later when it has been"
 	case mongo.ErrClientDisconnected, mongo.ErrWrongClient, mongo.ErrMissingResumeToken:
@@ -86,6 +103,31 @@ func HandleError(ctx *Context, err error) {
 		errString = "There was an issue while working with the database, please try again later"
 		log.Println(err)
 	default:
+		if IsTimeout(err) {
+			errString = "You did not respond in time"
+			break
+		}
+
+		if ambiguous, ok := err.(*AmbiguousError); ok {
+			// markdown isn't rendered in a code span, only a backtick could break out of it
+			errString = fmt.Sprintf("`%s` matches more than one result, please be more specific: %s",
+				strings.Replace(ambiguous.Query, "`", "'", -1), EscapeMarkdown(strings.Join(ambiguous.Candidates, ", ")))
+			break
+		}
+
+		if stage, ok := err.(*PipeStageError); ok {
+			reason := "does not exist"
+			if stage.Err == ErrCommandDisabled {
+				reason = "has been disabled here"
+			}
+			if name := stage.name(); name != "" {
+				errString = fmt.Sprintf("Command %d of the pipeline, `%s`, %s", stage.Stage, name, reason)
+			} else {
+				errString = fmt.Sprintf("Command %d of the pipeline is empty", stage.Stage)
+			}
+			break
+		}
+
 		if info, ok := err.(ErrBotHasNoPermissions); ok {
 			if info.Permission == "" {
 				errString = "The bot does not have the required permissions for the command that was ran, please make sure it has before running it again."
@@ -119,6 +161,7 @@ func HandlePanic(ctx *Context) {
 	if p == nil {
 		return
 	}
+	ctx.capture = nil
 
 	if e, ok := p.(error); ok {
 		HandleError(ctx, e)
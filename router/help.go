@@ -0,0 +1,81 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/auttaja/discordgo"
+)
+
+// HelpHandler is a ready-made help command.
+// Without arguments it lists all the commands grouped by category, leaving out the ones that are disabled
+// in the channel it was ran in. When given a command it shows the description, usage, aliases and subcommands of it
+func HelpHandler(ctx *Context) error {
+	root := ctx.Route.Root()
+
+	if len(ctx.Args) > 1 {
+		return commandHelp(ctx, root, ctx.Args[1:])
+	}
+
+	categories := map[string][]string{}
+	for _, rt := range root.Routes {
+		if rt.IsDisabledIn(ctx.Msg.GuildID, ctx.Msg.ChannelID) {
+			continue
+		}
+		category := rt.Category
+		if category == "" {
+			category = "Uncategorized"
+		}
+		categories[category] = append(categories[category], fmt.Sprintf("`%s`", rt.Name))
+	}
+
+	var names []string
+	for category := range categories {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+
+	em := discordgo.NewEmbed().SetTitle("Commands")
+	for _, category := range names {
+		em = em.AddField(category, strings.Join(categories[category], ", "), false)
+	}
+
+	_, err := ctx.ReplyEmbed(em)
+	return err
+}
+
+func commandHelp(ctx *Context, root *Route, path []string) error {
+	rt, depth := root.FindFull(path...)
+	if depth != len(path) {
+		return ErrNotFound
+	}
+
+	em := discordgo.NewEmbed().SetTitle(rt.FullName())
+	if rt.Description != "" {
+		em = em.SetDescription(rt.Description)
+	}
+	if rt.UsageString != "" {
+		em = em.AddField("Usage", fmt.Sprintf("`%s`", rt.UsageString), false)
+	}
+	if len(rt.Aliases) > 0 {
+		em = em.AddField("Aliases", strings.Join(rt.Aliases, ", "), false)
+	}
+
+	var subroutes []string
+	for _, sub := range rt.Routes {
+		if !sub.IsDisabledIn(ctx.Msg.GuildID, ctx.Msg.ChannelID) {
+			subroutes = append(subroutes, fmt.Sprintf("`%s`", sub.Name))
+		}
+	}
+	if len(subroutes) > 0 {
+		em = em.AddField("Subcommands", strings.Join(subroutes, ", "), false)
+	}
+
+	if rt.IsDisabledIn(ctx.Msg.GuildID, ctx.Msg.ChannelID) {
+		em = em.AddField("Disabled", "This command has been disabled here", false)
+	}
+
+	_, err := ctx.ReplyEmbed(em)
+	return err
+}
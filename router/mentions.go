@@ -0,0 +1,129 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+)
+
+// zeroWidthSpace breaks up mentions so Discord shows them as text
+const zeroWidthSpace = "\u200b"
+
+var (
+	mentionRegex = regexp.MustCompile(`<@(!|&)?(\d+)>|@(everyone|here)`)
+
+	markdownReplacer = strings.NewReplacer(
+		`\`, `\\`,
+		"*", `\*`,
+		"_", `\_`,
+		"~", `\~`,
+		"`", "\\`",
+		"|", `\|`,
+		">", `\>`,
+	)
+)
+
+// AllowedMentions lists what a message may mention, all other mentions get neutralized
+type AllowedMentions struct {
+	// Everyone allows @everyone and @here
+	Everyone bool
+	// Users are the IDs of the users that may be mentioned
+	Users []string
+	// Roles are the IDs of the roles that may be mentioned
+	Roles []string
+}
+
+func (a *AllowedMentions) allowsUser(id string) bool {
+	if a == nil {
+		return false
+	}
+	for _, u := range a.Users {
+		if u == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AllowedMentions) allowsRole(id string) bool {
+	if a == nil {
+		return false
+	}
+	for _, r := range a.Roles {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeMentions neutralizes all mentions in content that aren't allowed, so they show up as text
+// without notifying anyone. When allowed is nil no mentions are allowed
+func SanitizeMentions(content string, allowed *AllowedMentions) string {
+	return sanitizeMentions(content, allowed, nil)
+}
+
+// sanitizeMentions neutralizes the mentions that aren't allowed, name is used to show
+// the name of a user or role instead of the neutralized mention when possible
+func sanitizeMentions(content string, allowed *AllowedMentions, name func(role bool, id string) (string, bool)) string {
+	return mentionRegex.ReplaceAllStringFunc(content, func(m string) string {
+		match := mentionRegex.FindStringSubmatch(m)
+
+		if match[3] != "" {
+			if allowed != nil && allowed.Everyone {
+				return m
+			}
+			return "@" + zeroWidthSpace + match[3]
+		}
+
+		role := match[1] == "&"
+		if role && allowed.allowsRole(match[2]) || !role && allowed.allowsUser(match[2]) {
+			return m
+		}
+
+		if name != nil {
+			if n, ok := name(role, match[2]); ok {
+				return "@" + zeroWidthSpace + n
+			}
+		}
+		return "<@" + zeroWidthSpace + match[1] + match[2] + ">"
+	})
+}
+
+// EscapeMarkdown escapes the markdown in text, so user supplied text can safely be put in a message
+func EscapeMarkdown(text string) string {
+	return markdownReplacer.Replace(text)
+}
+
+// mentionName looks up the name to show for a neutralized mention in the state
+func (c *Context) mentionName(role bool, id string) (string, bool) {
+	if c.Msg == nil || c.Msg.GuildID == "" {
+		return "", false
+	}
+
+	if role {
+		g, err := c.Ses.State.Guild(c.Msg.GuildID)
+		if err != nil {
+			return "", false
+		}
+		for _, r := range g.Roles {
+			if r.ID == id {
+				return r.Name, true
+			}
+		}
+		return "", false
+	}
+
+	m, err := c.Ses.State.Member(c.Msg.GuildID, id)
+	if err != nil || m.User == nil {
+		return "", false
+	}
+	if m.Nick != "" {
+		return m.Nick, true
+	}
+	return m.User.Username, true
+}
+
+// sanitize neutralizes the mentions in content that the context does not allow
+func (c *Context) sanitize(content string, allowed *AllowedMentions) string {
+	return sanitizeMentions(content, allowed, c.mentionName)
+}
@@ -0,0 +1,53 @@
+package router
+
+import "testing"
+
+func TestSanitizeMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		allowed *AllowedMentions
+		want    string
+	}{
+		{"hello there", nil, "hello there"},
+		{"@everyone look", nil, "@\u200beveryone look"},
+		{"@here look", &AllowedMentions{}, "@\u200bhere look"},
+		{"@everyone look", &AllowedMentions{Everyone: true}, "@everyone look"},
+		{"hi <@123>", nil, "hi <@\u200b123>"},
+		{"hi <@!123>", nil, "hi <@\u200b!123>"},
+		{"hi <@123> and <@!456>", &AllowedMentions{Users: []string{"123"}}, "hi <@123> and <@\u200b!456>"},
+		{"<@&5> ping", &AllowedMentions{Roles: []string{"5"}}, "<@&5> ping"},
+		{"<@&5> ping", &AllowedMentions{Users: []string{"5"}}, "<@\u200b&5> ping"},
+		{"<@5> ping", &AllowedMentions{Roles: []string{"5"}}, "<@\u200b5> ping"},
+		{"<@abc> email@example.com", nil, "<@abc> email@example.com"},
+		{"héllo <@1> 🎉 @everyone", nil, "héllo <@\u200b1> 🎉 @\u200beveryone"},
+	}
+
+	for _, test := range tests {
+		if got := SanitizeMentions(test.content, test.allowed); got != test.want {
+			t.Errorf("%q: expected %q, got %q", test.content, test.want, got)
+		}
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"*bold* _italic_", `\*bold\* \_italic\_`},
+		{"~~strike~~", `\~\~strike\~\~`},
+		{"`code`", "\\`code\\`"},
+		{"||spoiler||", `\|\|spoiler\|\|`},
+		{"> quote", `\> quote`},
+		{`back\slash`, `back\\slash`},
+		{`\*`, `\\\*`},
+		{"ünïcödé *🎉*", `ünïcödé \*🎉\*`},
+	}
+
+	for _, test := range tests {
+		if got := EscapeMarkdown(test.text); got != test.want {
+			t.Errorf("%q: expected %q, got %q", test.text, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package router
+
+import "github.com/auttaja/discordgo"
+
+// RequireGuildPermission returns a middleware that only lets the handler run inside guilds,
+// for members that have the given permission(s) in the channel it was ran in
+func RequireGuildPermission(permission discordgo.PermissionOffset) MiddlewareFunc {
+	return func(fn HandlerFunc) HandlerFunc {
+		return func(ctx *Context) error {
+			if ctx.Msg.GuildID == "" {
+				return ErrNotAGuild
+			}
+
+			ok, err := ctx.AuthorHasPermission(permission)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrUserNoPermissions
+			}
+			return fn(ctx)
+		}
+	}
+}
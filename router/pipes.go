@@ -0,0 +1,116 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/auttaja/discordgo"
+)
+
+// maxPipeStages is the maximum amount of commands in a single pipeline
+const maxPipeStages = 5
+
+// errHandled is returned internally when a command failed and the error has already been shown to the user
+var errHandled = errors.New("error has been handled")
+
+// PipeStageError gets shown when a command after the first one of a pipeline doesn't exist or has been disabled
+type PipeStageError struct {
+	// Stage is the number of the command in the pipeline, starting at 1
+	Stage   int
+	Command string
+	// Err is ErrCouldNotFindRoute or ErrCommandDisabled
+	Err error
+}
+
+func (e *PipeStageError) Error() string {
+	return fmt.Sprintf("command %d of the pipeline (%s): %v", e.Stage, e.Command, e.Err)
+}
+
+// name returns the first word of the command, safe to show in a code span
+func (e *PipeStageError) name() string {
+	name := e.Command
+	if i := strings.IndexAny(name, " \n"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.Replace(name, "`", "'", -1)
+}
+
+// splitPipeline splits a command into the commands of the pipeline, with their prefix removed.
+// A pipe is only seen as one if it is followed by the prefix, so "-say a | b" is still a single command.
+// Pipes inside double quotes don't count either, and \| is a literal pipe
+func splitPipeline(command, prefix string) []string {
+	var stages []string
+	var current strings.Builder
+	quoted := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '\\' && i+1 < len(command) && command[i+1] == '|':
+			current.WriteByte('|')
+			i++
+			continue
+		case c == '"':
+			quoted = !quoted
+		case c == '|' && !quoted:
+			rest := strings.TrimLeft(command[i+1:], " ")
+			if strings.HasPrefix(rest, prefix) {
+				stages = append(stages, current.String())
+				current.Reset()
+				// continue right after the prefix
+				i = len(command) - len(rest) + len(prefix) - 1
+				continue
+			}
+		}
+		current.WriteByte(c)
+	}
+	stages = append(stages, current.String())
+
+	if len(stages) > 1 {
+		for i := range stages {
+			stages[i] = strings.TrimSpace(stages[i])
+		}
+	}
+	return stages
+}
+
+// Piped returns true if the output of the command gets piped into another command.
+// Reply, ReplyEmbed and the SendMessage functions then write to the output instead of sending a message
+func (c *Context) Piped() bool {
+	return c.capture != nil
+}
+
+// embedText turns an embed into text for when it gets piped into another command
+func embedText(embed *discordgo.MessageEmbed) string {
+	if embed == nil {
+		return ""
+	}
+
+	var lines []string
+	if embed.Title != "" {
+		lines = append(lines, embed.Title)
+	}
+	if embed.Description != "" {
+		lines = append(lines, embed.Description)
+	}
+	for _, f := range embed.Fields {
+		lines = append(lines, f.Name+": "+f.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// write adds text to the captured output of a piped command and returns a message
+// without ID standing in for the one that would have been sent
+func (c *Context) write(text string) *discordgo.Message {
+	if text != "" {
+		if c.capture.Len() > 0 {
+			c.capture.WriteString("\n")
+		}
+		c.capture.WriteString(text)
+	}
+	return &discordgo.Message{
+		ChannelID: c.Msg.ChannelID,
+		GuildID:   c.Msg.GuildID,
+		Content:   text,
+	}
+}
@@ -0,0 +1,215 @@
+package router
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/auttaja/discordgo"
+)
+
+func TestSplitPipeline(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ping", []string{"ping"}},
+		{"say hi | -upper", []string{"say hi", "upper"}},
+		{"say hi|-upper|-reverse", []string{"say hi", "upper", "reverse"}},
+		{"say a | b", []string{"say a | b"}},
+		{"say a | b | -upper", []string{"say a | b", "upper"}},
+		{`say "a | -b" | -upper`, []string{`say "a | -b"`, "upper"}},
+		{`say a \| -b`, []string{"say a | -b"}},
+		{`say a \| -b | -upper`, []string{"say a | -b", "upper"}},
+		{"say hi | - | -upper", []string{"say hi", "", "upper"}},
+		{"say hi | -", []string{"say hi", ""}},
+		{"| -upper", []string{"", "upper"}},
+	}
+
+	for _, test := range tests {
+		if got := splitPipeline(test.command, "-"); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: expected %q, got %q", test.command, test.want, got)
+		}
+	}
+
+	got := splitPipeline("say hi | <@1> upper", "<@1> ")
+	if want := []string{"say hi", "upper"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected a mention prefix to work, got %q", got)
+	}
+}
+
+// fakeDiscord answers every request to the REST API, passing the content of sent messages,
+// or the description of their embed, to the channel
+type fakeDiscord chan string
+
+func (f fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := "{}"
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/messages") {
+		var data discordgo.MessageSend
+		_ = json.NewDecoder(req.Body).Decode(&data)
+		if data.Content == "" && data.Embed != nil {
+			data.Content = data.Embed.Description
+		}
+		f <- data.Content
+		body = `{"id":"100","channel_id":"3","author":{"id":"5"}}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+// disabledRoutes is a ToggleStore disabling the routes with the names
+type disabledRoutes []string
+
+func (d disabledRoutes) IsDisabled(_, _ string, route *Route) (bool, error) {
+	for _, name := range d {
+		if route.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// pipeTest is a router with commands to pipe into each other, kept holds the input of the keep command
+type pipeTest struct {
+	t    *testing.T
+	r    *Route
+	s    *discordgo.Session
+	sent fakeDiscord
+	kept []string
+}
+
+func newPipeTest(t *testing.T) *pipeTest {
+	sent := make(fakeDiscord, 10)
+	s := &discordgo.Session{
+		State:        discordgo.NewState(),
+		StateEnabled: true,
+		Ratelimiter:  discordgo.NewRatelimiter(),
+		Client:       &http.Client{Transport: sent},
+	}
+	err := s.State.GuildAdd(&discordgo.Guild{
+		ID:       "2",
+		Channels: []*discordgo.Channel{{ID: "3", GuildID: "2"}},
+	}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := &pipeTest{t: t, r: New(), s: s, sent: sent}
+	test.r.Pipes = true
+	test.r.On("say", func(ctx *Context) error {
+		_, err := ctx.Reply(ctx.Args.After(1))
+		return err
+	})
+	test.r.On("upper", func(ctx *Context) error {
+		_, err := ctx.Reply(strings.ToUpper(ctx.Input))
+		return err
+	})
+	test.r.On("keep", func(ctx *Context) error {
+		test.kept = append(test.kept, ctx.Input)
+		return nil
+	})
+	return test
+}
+
+// run runs the command and returns the messages that were sent
+func (p *pipeTest) run(content string) ([]string, error) {
+	err := p.r.FindAndExecute(p.s, "-", "5", &discordgo.Message{
+		ID:        "4",
+		ChannelID: "3",
+		GuildID:   "2",
+		Author:    &discordgo.User{ID: "1"},
+		Content:   content,
+		Session:   p.s,
+	})
+
+	var sent []string
+	for {
+		select {
+		case content := <-p.sent:
+			sent = append(sent, content)
+		default:
+			return sent, err
+		}
+	}
+}
+
+func TestPipeOutput(t *testing.T) {
+	p := newPipeTest(t)
+
+	sent, err := p.run("-say hello world | -upper | -keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("expected the output to be piped instead of sent, sent %q", sent)
+	}
+	if len(p.kept) != 1 || p.kept[0] != "HELLO WORLD" {
+		t.Fatalf("expected each command to get the output of the one before it, kept %q", p.kept)
+	}
+
+	sent, err = p.run("-say hello | -upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0] != "HELLO" {
+		t.Fatalf("expected the last command to send its output, sent %q", sent)
+	}
+}
+
+func TestPipeStageError(t *testing.T) {
+	p := newPipeTest(t)
+
+	sent, err := p.run("-say hi | -missing arg | -keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "Command 2 of the pipeline, `missing`, does not exist") {
+		t.Fatalf("expected a missing later command to be reported, sent %q", sent)
+	}
+
+	p.r.Toggles = disabledRoutes{"upper"}
+	sent, err = p.run("-say hi | -keep | -upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "Command 3 of the pipeline, `upper`, has been disabled here") {
+		t.Fatalf("expected a disabled later command to be reported, sent %q", sent)
+	}
+
+	// the first command fails like any command that isn't piped
+	if _, err = p.run("-upper | -keep"); err != ErrCommandDisabled {
+		t.Fatalf("expected ErrCommandDisabled for a disabled first command, got %v", err)
+	}
+	if _, err = p.run("-missing | -keep"); err != ErrCouldNotFindRoute {
+		t.Fatalf("expected ErrCouldNotFindRoute for a missing first command, got %v", err)
+	}
+}
+
+func TestPipeStageLimit(t *testing.T) {
+	p := newPipeTest(t)
+
+	command := "-say hi" + strings.Repeat(" | -upper", maxPipeStages-2) + " | -keep"
+	if _, err := p.run(command); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.kept) != 1 || p.kept[0] != "HI" {
+		t.Fatalf("expected a pipeline of %d commands to run, kept %q", maxPipeStages, p.kept)
+	}
+
+	sent, err := p.run(command + " | -keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "You can only pipe up to 5 commands") {
+		t.Fatalf("expected a pipeline that is too long to be refused, sent %q", sent)
+	}
+	if len(p.kept) != 1 {
+		t.Fatal("expected none of the commands of a pipeline that is too long to run")
+	}
+}
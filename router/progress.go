@@ -0,0 +1,91 @@
+package router
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/auttaja/discordgo"
+)
+
+// typingInterval is how often the typing indicator gets renewed, Discord shows it for 10 seconds
+const typingInterval = 8 * time.Second
+
+// Typing makes the bot show that it is typing in the channel while the handler of this route runs,
+// if it takes longer than threshold
+func (r *Route) Typing(threshold time.Duration) *Route {
+	r.TypingAfter = threshold
+	return r
+}
+
+// keepTyping shows the typing indicator in the context channel from after the given duration,
+// until the returned function gets called
+func (c *Context) keepTyping(after time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		timer := time.NewTimer(after)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+		for {
+			_ = c.Ses.ChannelTyping(c.Msg.ChannelID)
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// Progress is a single status message that gets edited to show the progress of a slow command
+type Progress struct {
+	mu  sync.Mutex
+	ctx *Context
+	msg *discordgo.Message
+}
+
+// Progress sends a status message that can be updated while the command is working
+func (c *Context) Progress(status string) (*Progress, error) {
+	m, err := c.sendSplit(c.sanitize(status, c.AllowedMentions), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Progress{ctx: c, msg: m}, nil
+}
+
+// Update replaces the status message
+func (p *Progress) Update(status string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m, err := p.ctx.Ses.ChannelMessageEdit(p.msg.ChannelID, p.msg.ID, p.ctx.sanitize(status, p.ctx.AllowedMentions))
+	if err != nil {
+		return err
+	}
+	p.msg = m
+	return nil
+}
+
+// Step updates the status message to show the step the command is at, like "[2/5] Downloading"
+func (p *Progress) Step(step, total int, status string) error {
+	return p.Update(fmt.Sprintf("[%d/%d] %s", step, total, status))
+}
+
+// Message returns the status message
+func (p *Progress) Message() *discordgo.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.msg
+}
@@ -0,0 +1,188 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/auttaja/dgframework/events"
+	"github.com/auttaja/discordgo"
+)
+
+// DefaultPromptTimeout is how long Confirm and Choose wait for the user to react
+var DefaultPromptTimeout = time.Minute
+
+// ErrTooManyOptions gets returned by Choose when it is given more options than it has emojis for
+var ErrTooManyOptions = errors.New("too many options to choose from")
+
+const (
+	confirmEmoji = "\u2705"
+	cancelEmoji  = "\u274c"
+)
+
+// numberEmojis are the emojis Choose uses for the options
+var numberEmojis = []string{
+	"1\ufe0f\u20e3", "2\ufe0f\u20e3", "3\ufe0f\u20e3", "4\ufe0f\u20e3", "5\ufe0f\u20e3",
+	"6\ufe0f\u20e3", "7\ufe0f\u20e3", "8\ufe0f\u20e3", "9\ufe0f\u20e3", "\U0001f51f",
+}
+
+// TimeoutError gets returned when the user did not respond to a prompt in time
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("no response within %s", e.Timeout)
+}
+
+// IsTimeout returns true if the error is a TimeoutError
+func IsTimeout(err error) bool {
+	_, ok := err.(*TimeoutError)
+	return ok
+}
+
+// Context returns the context.Context of the command, it is never nil
+func (c *Context) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithContext sets the context.Context of the command, prompts stop waiting when it is done.
+// Commands get the BaseContext of the router, which the framework cancels when the bot closes
+func (c *Context) WithContext(ctx context.Context) *Context {
+	c.ctx = ctx
+	return c
+}
+
+// Prompt sends the question, if it isn't empty, and waits for the next message of the author in the channel.
+// It returns a TimeoutError if no message came within timeout, a timeout of 0 waits until the context is done
+func (c *Context) Prompt(question string, timeout time.Duration) (*discordgo.Message, error) {
+	if question != "" {
+		_, err := c.sendSplit(c.sanitize(question, c.AllowedMentions), nil, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	responses := make(chan *discordgo.Message, 1)
+	filter := events.Filter{ChannelID: c.Msg.ChannelID, UserID: c.Msg.Author.ID}
+	d := events.For(c.Ses)
+	remove := d.Add(discordgo.MessageCreate{}, filter, func(event interface{}) {
+		select {
+		case responses <- event.(*discordgo.MessageCreate).Message:
+		default:
+		}
+	})
+	defer remove()
+
+	timer, stop := promptTimer(timeout)
+	defer stop()
+
+	select {
+	case m := <-responses:
+		return m, nil
+	case <-timer:
+		return nil, &TimeoutError{Timeout: timeout}
+	case <-c.Context().Done():
+		return nil, c.Context().Err()
+	case <-d.Done():
+		return nil, context.Canceled
+	}
+}
+
+// Confirm sends the question with a confirm and a cancel reaction, and returns true if the author
+// reacted with confirm or false if they reacted with cancel. It waits for DefaultPromptTimeout
+func (c *Context) Confirm(question string) (bool, error) {
+	return c.ConfirmTimeout(question, DefaultPromptTimeout)
+}
+
+// ConfirmTimeout is Confirm with its own timeout, a timeout of 0 waits until the context is done
+func (c *Context) ConfirmTimeout(question string, timeout time.Duration) (bool, error) {
+	i, err := c.reactionPrompt(question, []string{confirmEmoji, cancelEmoji}, timeout)
+	if err != nil {
+		return false, err
+	}
+	return i == 0, nil
+}
+
+// Choose lists the options with a number reaction for each and returns the index of the option
+// the author reacted with. It waits for DefaultPromptTimeout and takes at most 10 options
+func (c *Context) Choose(options []string) (int, error) {
+	return c.ChooseTimeout(options, DefaultPromptTimeout)
+}
+
+// ChooseTimeout is Choose with its own timeout, a timeout of 0 waits until the context is done
+func (c *Context) ChooseTimeout(options []string, timeout time.Duration) (int, error) {
+	if len(options) > len(numberEmojis) {
+		return 0, ErrTooManyOptions
+	}
+
+	lines := make([]string, len(options))
+	for i, o := range options {
+		lines[i] = fmt.Sprintf("%s %s", numberEmojis[i], o)
+	}
+
+	return c.reactionPrompt(strings.Join(lines, "\n"), numberEmojis[:len(options)], timeout)
+}
+
+// reactionPrompt sends the content with the emojis as reactions and returns the index of the emoji the author reacted with
+func (c *Context) reactionPrompt(content string, emojis []string, timeout time.Duration) (int, error) {
+	m, err := c.sendSplit(c.sanitize(content, c.AllowedMentions), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	responses := make(chan int, 1)
+	filter := events.Filter{ChannelID: m.ChannelID, UserID: c.Msg.Author.ID}
+	d := events.For(c.Ses)
+	remove := d.Add(discordgo.MessageReactionAdd{}, filter, func(event interface{}) {
+		r := event.(*discordgo.MessageReactionAdd)
+		if r.MessageID != m.ID {
+			return
+		}
+		for i, e := range emojis {
+			if r.Emoji.Name == e {
+				select {
+				case responses <- i:
+				default:
+				}
+				return
+			}
+		}
+	})
+	defer remove()
+
+	for _, e := range emojis {
+		err = c.Ses.MessageReactionAdd(m.ChannelID, m.ID, e)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	timer, stop := promptTimer(timeout)
+	defer stop()
+
+	select {
+	case i := <-responses:
+		return i, nil
+	case <-timer:
+		return 0, &TimeoutError{Timeout: timeout}
+	case <-c.Context().Done():
+		return 0, c.Context().Err()
+	case <-d.Done():
+		return 0, context.Canceled
+	}
+}
+
+// promptTimer returns a channel that fires after timeout, or never if timeout is 0
+func promptTimer(timeout time.Duration) (<-chan time.Time, func()) {
+	if timeout <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTimer(timeout)
+	return t.C, func() { t.Stop() }
+}
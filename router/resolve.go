@@ -0,0 +1,336 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/auttaja/discordgo"
+)
+
+// maxCandidates is the maximum amount of candidates an AmbiguousError lists
+const maxCandidates = 10
+
+// How well a name matches a query, higher is better
+const (
+	matchNone = iota
+	matchContains
+	matchPrefix
+	matchExactFold
+	matchExact
+)
+
+var (
+	userMentionRegex    = regexp.MustCompile(`^<@!?(\d+)>$`)
+	roleMentionRegex    = regexp.MustCompile(`^<@&(\d+)>$`)
+	channelMentionRegex = regexp.MustCompile(`^<#(\d+)>$`)
+	idRegex             = regexp.MustCompile(`^\d{15,21}$`)
+)
+
+// AmbiguousError gets returned when a query matches multiple members, roles or channels equally well
+type AmbiguousError struct {
+	Query string
+	// Candidates are the names of the matches, at most 10 of them
+	Candidates []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%q matches multiple results: %s", e.Query, strings.Join(e.Candidates, ", "))
+}
+
+// matchName returns how well name matches the query
+func matchName(name, query string) int {
+	switch {
+	case name == "":
+		return matchNone
+	case name == query:
+		return matchExact
+	case strings.EqualFold(name, query):
+		return matchExactFold
+	}
+
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	switch {
+	case strings.HasPrefix(name, query):
+		return matchPrefix
+	case strings.Contains(name, query):
+		return matchContains
+	}
+	return matchNone
+}
+
+// mentionedID returns the ID in the query if it is a mention matching the regex or an ID
+func mentionedID(query string, mention *regexp.Regexp) string {
+	if match := mention.FindStringSubmatch(query); match != nil {
+		return match[1]
+	}
+	if idRegex.MatchString(query) {
+		return query
+	}
+	return ""
+}
+
+// best keeps track of the best matches while searching
+type best struct {
+	score int
+	items []int
+}
+
+func (b *best) add(i, score int) {
+	switch {
+	case score == matchNone || score < b.score:
+	case score > b.score:
+		b.score = score
+		b.items = []int{i}
+	default:
+		b.items = append(b.items, i)
+	}
+}
+
+// result turns the best matches into an index, ErrNotFound or an AmbiguousError
+func (b *best) result(query string, name func(i int) string) (int, error) {
+	switch len(b.items) {
+	case 0:
+		return 0, ErrNotFound
+	case 1:
+		return b.items[0], nil
+	}
+
+	err := &AmbiguousError{Query: query}
+	for _, i := range b.items {
+		if len(err.Candidates) == maxCandidates {
+			break
+		}
+		err.Candidates = append(err.Candidates, name(i))
+	}
+	return 0, err
+}
+
+// lookup retrieves the guilds, members and channels a query is resolved against
+type lookup struct {
+	state   discordgo.StateCache
+	guild   func(guildID string) (*discordgo.Guild, error)
+	member  func(guildID, userID string) (*discordgo.Member, error)
+	channel func(channelID string) (*discordgo.Channel, error)
+}
+
+// sessionLookup looks objects up in the state, falling back to the REST API
+func sessionLookup(s *discordgo.Session) *lookup {
+	return &lookup{
+		state: s.State,
+		guild: func(guildID string) (*discordgo.Guild, error) {
+			g, err := s.State.Guild(guildID)
+			if err != nil {
+				g, err = s.Guild(guildID)
+			}
+			return g, err
+		},
+		member: func(guildID, userID string) (*discordgo.Member, error) {
+			m, err := s.State.Member(guildID, userID)
+			if err != nil {
+				m, err = s.GuildMember(guildID, userID)
+			}
+			return m, err
+		},
+		channel: func(channelID string) (*discordgo.Channel, error) {
+			ch, err := s.State.Channel(channelID)
+			if err != nil {
+				ch, err = s.Channel(channelID)
+			}
+			return ch, err
+		},
+	}
+}
+
+// contextLookup looks objects up with the getters of the context, which use the StateCache of the router
+func contextLookup(c *Context) *lookup {
+	return &lookup{
+		state:   c.Ses.State,
+		guild:   c.GetGuild,
+		member:  c.GetMember,
+		channel: c.GetChannel,
+	}
+}
+
+// readLock read locks the state if it is the in-memory state of discordgo, which the gateway updates in place.
+// The members, roles and channels of a guild may only be searched while it is held. It returns the unlock function
+func (l *lookup) readLock() func() {
+	if st, ok := l.state.(*discordgo.State); ok {
+		st.RLock()
+		return st.RUnlock
+	}
+	return func() {}
+}
+
+// ResolveMember finds a member of the guild by mention, ID, username#discriminator, username or nickname.
+// Exact matches win over case insensitive ones, which win over names starting with the query, which win
+// over names containing it. It returns ErrNotFound if nothing matches or the query is empty, or an
+// AmbiguousError if multiple members match equally well.
+// Names can only be looked up in guilds whose members have been requested from the gateway, as the REST API
+// doesn't return the members of a guild. If the guild isn't chunked and no member matches, ErrMembersNotCached
+// gets returned instead of ErrNotFound
+func ResolveMember(s *discordgo.Session, guildID, query string) (*discordgo.Member, error) {
+	return sessionLookup(s).resolveMember(guildID, query)
+}
+
+// ResolveRole finds a role of the guild by mention, ID or name, ranking matches like ResolveMember
+func ResolveRole(s *discordgo.Session, guildID, query string) (*discordgo.Role, error) {
+	return sessionLookup(s).resolveRole(guildID, query)
+}
+
+// ResolveChannel finds a channel of the guild by mention, ID or name, ranking matches like ResolveMember.
+// A leading # in the query is ignored
+func ResolveChannel(s *discordgo.Session, guildID, query string) (*discordgo.Channel, error) {
+	return sessionLookup(s).resolveChannel(guildID, query)
+}
+
+func (l *lookup) resolveMember(guildID, query string) (*discordgo.Member, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrNotFound
+	}
+
+	if id := mentionedID(query, userMentionRegex); id != "" {
+		return l.member(guildID, id)
+	}
+
+	g, err := l.guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := l.readLock()
+	var b best
+	for i, m := range g.Members {
+		if m.User == nil {
+			continue
+		}
+
+		score := matchName(m.User.Username, query)
+		if nick := matchName(m.Nick, query); nick > score {
+			score = nick
+		}
+		if m.User.Username+"#"+m.User.Discriminator == query {
+			score = matchExact
+		}
+		b.add(i, score)
+	}
+
+	i, err := b.result(query, func(i int) string {
+		return g.Members[i].User.Username + "#" + g.Members[i].User.Discriminator
+	})
+	var m *discordgo.Member
+	if err == nil {
+		m = g.Members[i]
+	} else if err == ErrNotFound && len(g.Members) < g.MemberCount {
+		err = ErrMembersNotCached
+	}
+	unlock()
+	return m, err
+}
+
+func (l *lookup) resolveRole(guildID, query string) (*discordgo.Role, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrNotFound
+	}
+
+	g, err := l.guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := l.readLock()
+	defer unlock()
+
+	if id := mentionedID(query, roleMentionRegex); id != "" {
+		for _, r := range g.Roles {
+			if r.ID == id {
+				return r, nil
+			}
+		}
+		return nil, ErrNotFound
+	}
+
+	var b best
+	for i, r := range g.Roles {
+		b.add(i, matchName(r.Name, query))
+	}
+
+	i, err := b.result(query, func(i int) string {
+		return g.Roles[i].Name
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g.Roles[i], nil
+}
+
+func (l *lookup) resolveChannel(guildID, query string) (*discordgo.Channel, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrNotFound
+	}
+
+	if id := mentionedID(query, channelMentionRegex); id != "" {
+		ch, err := l.channel(id)
+		if err != nil {
+			return nil, err
+		}
+		if ch.GuildID != guildID {
+			return nil, ErrNotFound
+		}
+		return ch, nil
+	}
+
+	query = strings.TrimSpace(strings.TrimPrefix(query, "#"))
+	if query == "" {
+		return nil, ErrNotFound
+	}
+
+	g, err := l.guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := l.readLock()
+	defer unlock()
+
+	var b best
+	for i, ch := range g.Channels {
+		b.add(i, matchName(ch.Name, query))
+	}
+
+	i, err := b.result(query, func(i int) string {
+		return "#" + g.Channels[i].Name
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g.Channels[i], nil
+}
+
+// ResolveMember finds a member of the context guild, see ResolveMember.
+// Lookups that miss the state go through the StateCache of the router
+func (c *Context) ResolveMember(query string) (*discordgo.Member, error) {
+	if c.Msg.GuildID == "" {
+		return nil, ErrNotAGuild
+	}
+	return contextLookup(c).resolveMember(c.Msg.GuildID, query)
+}
+
+// ResolveRole finds a role of the context guild, see ResolveRole
+func (c *Context) ResolveRole(query string) (*discordgo.Role, error) {
+	if c.Msg.GuildID == "" {
+		return nil, ErrNotAGuild
+	}
+	return contextLookup(c).resolveRole(c.Msg.GuildID, query)
+}
+
+// ResolveChannel finds a channel of the context guild, see ResolveChannel
+func (c *Context) ResolveChannel(query string) (*discordgo.Channel, error) {
+	if c.Msg.GuildID == "" {
+		return nil, ErrNotAGuild
+	}
+	return contextLookup(c).resolveChannel(c.Msg.GuildID, query)
+}
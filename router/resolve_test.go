@@ -0,0 +1,89 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/auttaja/discordgo"
+)
+
+func resolveSession(memberCount int) *discordgo.Session {
+	s := &discordgo.Session{State: discordgo.NewState(), StateEnabled: true}
+	_ = s.State.GuildAdd(&discordgo.Guild{
+		ID:          "1",
+		MemberCount: memberCount,
+		Members: []*discordgo.Member{
+			{GuildID: "1", User: &discordgo.User{ID: "10", Username: "alice", Discriminator: "0001"}},
+			{GuildID: "1", User: &discordgo.User{ID: "11", Username: "Alicia", Discriminator: "0002"}, Nick: "ali"},
+			{GuildID: "1", User: &discordgo.User{ID: "12", Username: "bob", Discriminator: "0003"}},
+		},
+		Roles: []*discordgo.Role{
+			{ID: "20", Name: "Moderator"},
+			{ID: "21", Name: "Mods"},
+		},
+		Channels: []*discordgo.Channel{
+			{ID: "30", GuildID: "1", Name: "general"},
+		},
+	}, s)
+	return s
+}
+
+func TestResolveMember(t *testing.T) {
+	s := resolveSession(3)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"alice", "10"},
+		{"ALI", "11"},
+		{"Alicia#0002", "11"},
+		{"<@!12>", "12"},
+		{"bo", "12"},
+	}
+	for _, test := range tests {
+		m, err := ResolveMember(s, "1", test.query)
+		if err != nil {
+			t.Errorf("%q: %s", test.query, err)
+			continue
+		}
+		if m.User.ID != test.want {
+			t.Errorf("%q: expected member %s, got %s", test.query, test.want, m.User.ID)
+		}
+	}
+
+	if _, err := ResolveMember(s, "1", "li"); err == nil {
+		t.Error("expected an AmbiguousError for a query matching two members")
+	} else if _, ok := err.(*AmbiguousError); !ok {
+		t.Errorf("expected an AmbiguousError, got %s", err)
+	}
+	if _, err := ResolveMember(s, "1", "carol"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound in a chunked guild, got %v", err)
+	}
+	if _, err := ResolveMember(resolveSession(100), "1", "carol"); err != ErrMembersNotCached {
+		t.Errorf("expected ErrMembersNotCached in a guild that isn't chunked, got %v", err)
+	}
+	if _, err := ResolveMember(s, "1", "  "); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for an empty query, got %v", err)
+	}
+}
+
+func TestResolveRoleAndChannel(t *testing.T) {
+	s := resolveSession(3)
+
+	r, err := ResolveRole(s, "1", "mods")
+	if err != nil || r.ID != "21" {
+		t.Errorf("expected role 21, got %v, %v", r, err)
+	}
+	r, err = ResolveRole(s, "1", "<@&20>")
+	if err != nil || r.ID != "20" {
+		t.Errorf("expected role 20, got %v, %v", r, err)
+	}
+
+	ch, err := ResolveChannel(s, "1", "#gen")
+	if err != nil || ch.ID != "30" {
+		t.Errorf("expected channel 30, got %v, %v", ch, err)
+	}
+	if _, err = ResolveChannel(s, "1", "#"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for an empty channel name, got %v", err)
+	}
+}
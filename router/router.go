@@ -1,8 +1,11 @@
 package router
 
 import (
+	"context"
+	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/auttaja/discordgo"
 )
@@ -183,6 +186,32 @@ type Route struct {
 
 	// Middleware to be applied when adding subroutes
 	Middleware []MiddlewareFunc
+
+	// TypingAfter is how long the handler may take before the bot shows that it is typing, 0 disables it
+	TypingAfter time.Duration
+
+	// Toggles gets consulted before executing a route to see if it has been disabled,
+	// only used on the root route
+	Toggles ToggleStore
+
+	// GuildAliases expands the aliases guilds defined before the route gets searched,
+	// only used on the root route
+	GuildAliases AliasResolver
+
+	// Fallback gets called when no route matched the command, the first argument being the command.
+	// It should return ErrCouldNotFindRoute if it can't handle the command either, only used on the root route
+	Fallback HandlerFunc
+
+	// StateCache is used by the Context getters for lookups that miss the state, only used on the root route
+	StateCache *StateCache
+
+	// BaseContext is the context.Context of every command, so prompts stop waiting when it is done.
+	// Only used on the root route
+	BaseContext context.Context
+
+	// Pipes enables piping the output of a command into the next one, for example "-search foo | -translate de",
+	// only used on the root route
+	Pipes bool
 }
 
 // Desc sets this routes description
@@ -209,6 +238,25 @@ func (r *Route) Alias(aliases ...string) *Route {
 	return r
 }
 
+// Root returns the top-most route of the tree this route belongs to
+func (r *Route) Root() *Route {
+	rt := r
+	for rt.Parent != nil {
+		rt = rt.Parent
+	}
+	return rt
+}
+
+// FullName returns the names of this route and all its parents, excluding the root,
+// separated by spaces. For example "role add" for the add subroute of the role command
+func (r *Route) FullName() string {
+	var names []string
+	for rt := r; rt != nil && rt.Parent != nil; rt = rt.Parent {
+		names = append([]string{rt.Name}, names...)
+	}
+	return strings.Join(names, string(separator))
+}
+
 func mention(id string) string {
 	return "<@" + id + ">"
 }
@@ -254,19 +302,100 @@ func (r *Route) FindAndExecute(s *discordgo.Session, prefix string, botID string
 	}
 
 	command := strings.TrimPrefix(m.Content, pf)
+
+	stages := []string{command}
+	if r.Pipes {
+		stages = splitPipeline(command, pf)
+		if len(stages) > maxPipeStages {
+			HandleError(NewContext(s, m, nil, r), ErrPipelineTooLong)
+			return nil
+		}
+	}
+
+	var input string
+	for i, stage := range stages {
+		output, err := r.execute(s, m, stage, input, i < len(stages)-1)
+		if err == errHandled {
+			return nil
+		}
+		if err != nil && i > 0 && (err == ErrCouldNotFindRoute || err == ErrCommandDisabled) {
+			// the commands before it already ran, so their output can't just disappear
+			HandleError(NewContext(s, m, nil, r), &PipeStageError{Stage: i + 1, Command: stage, Err: err})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		input = output
+	}
+
+	return nil
+}
+
+// execute finds and runs the route for a single command without prefix, input is the output of the previous
+// command if it is part of a pipeline. When capture is true the output of the command gets returned instead of sent.
+// It returns errHandled if the handler failed and the error has already been shown to the user
+func (r *Route) execute(s *discordgo.Session, m *discordgo.Message, command, input string, capture bool) (output string, err error) {
 	args := ParseArgs(command)
 
+	if r.GuildAliases != nil && m.GuildID != "" {
+		if expanded, err := r.GuildAliases.Expand(m.GuildID, args); err != nil {
+			log.Println("Unable to expand the guild aliases, running the command as is: ", err)
+		} else {
+			args = expanded
+		}
+	}
+
+	var ctx *Context
+	var handler HandlerFunc
+	var typingAfter time.Duration
 	if rt, depth := r.FindFull(args...); depth > 0 {
-		args = append([]string{strings.Join(args[:depth], string(separator))}, args[depth:]...)
-		ctx := NewContext(s, m, args, rt)
-		defer HandlePanic(ctx)
-		err := rt.Handler(ctx)
-		if err != nil {
-			HandleError(ctx, err)
+		if rt.IsDisabledIn(m.GuildID, m.ChannelID) {
+			return "", ErrCommandDisabled
 		}
+
+		args = append([]string{strings.Join(args[:depth], string(separator))}, args[depth:]...)
+		ctx = NewContext(s, m, args, rt)
+		handler = rt.Handler
+		typingAfter = rt.TypingAfter
+	} else if r.Fallback != nil && args.Get(0) != "" {
+		ctx = NewContext(s, m, args, r)
+		handler = r.Fallback
 	} else {
-		return ErrCouldNotFindRoute
+		return "", ErrCouldNotFindRoute
 	}
 
-	return nil
+	if r.BaseContext != nil {
+		ctx.WithContext(r.BaseContext)
+	}
+	if input != "" {
+		ctx.Input = input
+		ctx.Args = append(ctx.Args, input)
+	}
+	if capture {
+		ctx.capture = &strings.Builder{}
+	}
+
+	// stays the result if the handler panics, HandlePanic shows the panic to the user
+	output, err = "", errHandled
+	defer HandlePanic(ctx)
+
+	if typingAfter > 0 && !capture {
+		stopTyping := ctx.keepTyping(typingAfter)
+		defer stopTyping()
+	}
+
+	handlerErr := handler(ctx)
+	if handlerErr == ErrCouldNotFindRoute && ctx.Route == r {
+		return "", handlerErr
+	}
+	if handlerErr != nil {
+		HandleError(ctx, handlerErr)
+		return "", errHandled
+	}
+
+	if capture {
+		return ctx.capture.String(), nil
+	}
+	return "", nil
 }
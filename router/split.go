@@ -0,0 +1,280 @@
+package router
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/auttaja/discordgo"
+)
+
+// Discord's limits on the length of messages and embeds
+const (
+	MaxMessageLength = 2000
+
+	MaxEmbedLength      = 6000
+	MaxEmbedFields      = 25
+	MaxEmbedTitle       = 256
+	MaxEmbedDescription = 4096
+	MaxFieldName        = 256
+	MaxFieldValue       = 1024
+	MaxFooterText       = 2048
+	MaxAuthorName       = 256
+)
+
+const codeFence = "```"
+
+// SplitMessage splits content into parts that are at most limit characters long, splitting on line boundaries
+// where possible. When a code block gets split it is closed at the end of the part and reopened, with the
+// same language, at the start of the next one
+func SplitMessage(content string, limit int) []string {
+	if runeLength(content) <= limit {
+		if content == "" {
+			return nil
+		}
+		return []string{content}
+	}
+
+	var parts []string
+	var current strings.Builder
+	var size int
+	var inCode bool
+	var language string
+
+	write := func(text string) {
+		current.WriteString(text)
+		size += runeLength(text)
+	}
+	flush := func() {
+		if size == 0 {
+			return
+		}
+		if inCode {
+			write("\n" + codeFence)
+		}
+		parts = append(parts, current.String())
+		current.Reset()
+		size = 0
+		if inCode {
+			write(codeFence + language)
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		// room needed to close an open code block
+		closing := 0
+		if inCode {
+			closing = len("\n" + codeFence)
+		}
+
+		for _, piece := range splitLine(line, limit-len(codeFence)-runeLength(language)-closing-1) {
+			if size > 0 && size+1+runeLength(piece)+closing > limit {
+				flush()
+			}
+			if size > 0 {
+				write("\n")
+			}
+			write(piece)
+		}
+
+		if strings.Count(line, codeFence)%2 == 1 {
+			inCode = !inCode
+			language = ""
+			if inCode && strings.HasPrefix(strings.TrimSpace(line), codeFence) {
+				language = strings.TrimSpace(strings.TrimSpace(line)[len(codeFence):])
+				if strings.ContainsAny(language, " `") {
+					language = ""
+				}
+			}
+		}
+	}
+
+	// the last part doesn't need the code block to be closed, the content itself closes it if it wanted to
+	if size > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
+// splitLine splits a single line into pieces of at most limit characters, preferring to split on spaces
+func splitLine(line string, limit int) []string {
+	if limit <= 0 || runeLength(line) <= limit {
+		return []string{line}
+	}
+
+	var pieces []string
+	for runeLength(line) > limit {
+		end := runeOffset(line, limit)
+		cut := strings.LastIndex(line[:end], " ")
+		if cut <= 0 {
+			cut = end
+		}
+		pieces = append(pieces, line[:cut])
+		line = strings.TrimLeft(line[cut:], " ")
+	}
+	return append(pieces, line)
+}
+
+// SplitEmbed splits an embed that exceeds Discord's limits into multiple embeds that don't.
+// The title, author and thumbnail stay on the first embed, the footer, image and timestamp move
+// to the last one. Field values that are too long continue in fields without a name
+func SplitEmbed(embed *discordgo.MessageEmbed) []*discordgo.MessageEmbed {
+	if embed == nil {
+		return nil
+	}
+	if embedFits(embed) {
+		return []*discordgo.MessageEmbed{embed}
+	}
+
+	first := &discordgo.MessageEmbed{
+		URL:       embed.URL,
+		Type:      embed.Type,
+		Title:     truncate(embed.Title, MaxEmbedTitle),
+		Color:     embed.Color,
+		Author:    embed.Author,
+		Thumbnail: embed.Thumbnail,
+	}
+	embeds := []*discordgo.MessageEmbed{first}
+	current := first
+	size := runeLength(first.Title)
+	if first.Author != nil {
+		size += runeLength(first.Author.Name)
+	}
+
+	next := func() {
+		current = &discordgo.MessageEmbed{Color: embed.Color}
+		embeds = append(embeds, current)
+		size = 0
+	}
+
+	for i, part := range SplitMessage(embed.Description, MaxEmbedDescription) {
+		if i > 0 || size+runeLength(part) > MaxEmbedLength {
+			next()
+		}
+		current.Description = part
+		size += runeLength(part)
+	}
+
+	for _, f := range embed.Fields {
+		values := SplitMessage(f.Value, MaxFieldValue)
+		if len(values) == 0 {
+			values = []string{f.Value}
+		}
+
+		for i, value := range values {
+			name := truncate(f.Name, MaxFieldName)
+			if i > 0 {
+				name = zeroWidthSpace
+			}
+			if len(current.Fields) >= MaxEmbedFields || size+runeLength(name)+runeLength(value) > MaxEmbedLength {
+				next()
+			}
+			current.Fields = append(current.Fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  value,
+				Inline: f.Inline,
+			})
+			size += runeLength(name) + runeLength(value)
+		}
+	}
+
+	if embed.Footer != nil && size+runeLength(embed.Footer.Text) > MaxEmbedLength {
+		next()
+	}
+	current.Footer = embed.Footer
+	current.Image = embed.Image
+	current.Timestamp = embed.Timestamp
+
+	return embeds
+}
+
+// embedFits returns true if the embed is within all of Discord's limits
+func embedFits(embed *discordgo.MessageEmbed) bool {
+	size := runeLength(embed.Title) + runeLength(embed.Description)
+	if runeLength(embed.Title) > MaxEmbedTitle || runeLength(embed.Description) > MaxEmbedDescription || len(embed.Fields) > MaxEmbedFields {
+		return false
+	}
+	for _, f := range embed.Fields {
+		if runeLength(f.Name) > MaxFieldName || runeLength(f.Value) > MaxFieldValue {
+			return false
+		}
+		size += runeLength(f.Name) + runeLength(f.Value)
+	}
+	if embed.Footer != nil {
+		size += runeLength(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		size += runeLength(embed.Author.Name)
+	}
+	return size <= MaxEmbedLength
+}
+
+// truncate cuts text off at limit characters
+func truncate(text string, limit int) string {
+	return text[:runeOffset(text, limit)]
+}
+
+// runeLength returns the amount of characters in text, which is what Discord's limits count
+func runeLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// runeOffset returns the byte index at which the character after the first n characters of text starts,
+// or the length of text if it is shorter
+func runeOffset(text string, n int) int {
+	for i := range text {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(text)
+}
+
+// sendSplit sends the content and embed, split into as many messages as needed, to the context channel.
+// The files are attached to the last message, which is also the one that gets returned.
+// When the content needs more than MaxReplyMessages messages it gets uploaded as a file instead
+func (c *Context) sendSplit(content string, embed *discordgo.MessageEmbed, files []*discordgo.File) (*discordgo.Message, error) {
+	parts := SplitMessage(content, MaxMessageLength)
+	if c.MaxReplyMessages > 0 && len(parts) > c.MaxReplyMessages {
+		files = append(files, &discordgo.File{
+			Name:        "message.txt",
+			ContentType: "text/plain",
+			Reader:      strings.NewReader(content),
+		})
+		parts = []string{"The message was too long, so it has been attached as a file"}
+	}
+
+	type message struct {
+		content string
+		embed   *discordgo.MessageEmbed
+	}
+
+	var messages []*message
+	for _, part := range parts {
+		messages = append(messages, &message{content: part})
+	}
+	for i, em := range SplitEmbed(embed) {
+		if i == 0 && len(messages) > 0 {
+			messages[len(messages)-1].embed = em
+		} else {
+			messages = append(messages, &message{embed: em})
+		}
+	}
+	if len(messages) == 0 {
+		messages = append(messages, &message{})
+	}
+
+	var m *discordgo.Message
+	var err error
+	for i, msg := range messages {
+		var attached []*discordgo.File
+		if i == len(messages)-1 {
+			attached = files
+		}
+		m, err = c.Channel.SendMessage(msg.content, msg.embed, attached)
+		if err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/auttaja/discordgo"
+)
+
+func checkParts(t *testing.T, name string, parts []string, limit int) {
+	t.Helper()
+	for i, part := range parts {
+		if n := utf8.RuneCountInString(part); n > limit {
+			t.Errorf("%s: part %d is %d characters long, the limit is %d", name, i+1, n, limit)
+		}
+		if !utf8.ValidString(part) {
+			t.Errorf("%s: part %d splits up a character", name, i+1)
+		}
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		parts   int
+		// joined is what the parts are joined with to get the content back
+		joined string
+	}{
+		{"empty", "", 0, ""},
+		{"short", "hello", 1, ""},
+		{"at the limit", strings.Repeat("a", MaxMessageLength), 1, ""},
+		{"over the limit", strings.Repeat("a", MaxMessageLength+1), 2, ""},
+		{"lines", strings.Repeat(strings.Repeat("a", 999)+"\n", 3), 2, "\n"},
+		{"words", strings.Repeat("word ", 1000), 3, " "},
+		{"multi-byte at the limit", strings.Repeat("é", MaxMessageLength), 1, ""},
+		{"multi-byte over the limit", strings.Repeat("é", MaxMessageLength+1), 2, ""},
+		{"emoji over the limit", strings.Repeat("🎉", MaxMessageLength+1), 2, ""},
+	}
+
+	for _, test := range tests {
+		parts := SplitMessage(test.content, MaxMessageLength)
+		if len(parts) != test.parts {
+			t.Errorf("%s: expected %d parts, got %d", test.name, test.parts, len(parts))
+			continue
+		}
+		checkParts(t, test.name, parts, MaxMessageLength)
+		if got := strings.Join(parts, test.joined); strings.TrimRight(got, " \n") != strings.TrimRight(test.content, " \n") {
+			t.Errorf("%s: the parts don't add up to the content", test.name)
+		}
+	}
+}
+
+func TestSplitMessageCodeBlock(t *testing.T) {
+	content := "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 200) + "```"
+	parts := SplitMessage(content, MaxMessageLength)
+	if len(parts) < 2 {
+		t.Fatalf("expected the code block to be split, got %d parts", len(parts))
+	}
+	checkParts(t, "code block", parts, MaxMessageLength)
+
+	for i, part := range parts {
+		if strings.Count(part, codeFence)%2 != 0 {
+			t.Errorf("part %d doesn't close its code block", i+1)
+		}
+		if !strings.HasPrefix(part, "```go\n") {
+			t.Errorf("part %d doesn't open the code block with the language", i+1)
+		}
+	}
+}
+
+func checkEmbeds(t *testing.T, name string, embeds []*discordgo.MessageEmbed) {
+	t.Helper()
+	for i, em := range embeds {
+		if !embedFits(em) {
+			t.Errorf("%s: embed %d doesn't fit", name, i+1)
+		}
+		if !utf8.ValidString(em.Description) {
+			t.Errorf("%s: embed %d splits up a character", name, i+1)
+		}
+		for _, f := range em.Fields {
+			if !utf8.ValidString(f.Value) {
+				t.Errorf("%s: a field of embed %d splits up a character", name, i+1)
+			}
+		}
+	}
+}
+
+func TestSplitEmbed(t *testing.T) {
+	fields := func(n, length int) []*discordgo.MessageEmbedField {
+		var fields []*discordgo.MessageEmbedField
+		for i := 0; i < n; i++ {
+			fields = append(fields, &discordgo.MessageEmbedField{Name: "name", Value: strings.Repeat("v", length)})
+		}
+		return fields
+	}
+
+	tests := []struct {
+		name   string
+		embed  *discordgo.MessageEmbed
+		embeds int
+	}{
+		{"description at the limit", &discordgo.MessageEmbed{Description: strings.Repeat("a", MaxEmbedDescription)}, 1},
+		{"description over the limit", &discordgo.MessageEmbed{Description: strings.Repeat("a", MaxEmbedDescription+1)}, 2},
+		{"multi-byte description at the limit", &discordgo.MessageEmbed{Description: strings.Repeat("ü", MaxEmbedDescription)}, 1},
+		{"multi-byte description over the limit", &discordgo.MessageEmbed{Description: strings.Repeat("ü", MaxEmbedDescription+1)}, 2},
+		{"embed at the limit", &discordgo.MessageEmbed{Description: strings.Repeat("a", 2000), Fields: fields(4, 996)}, 1},
+		{"embed over the limit", &discordgo.MessageEmbed{Description: strings.Repeat("a", 2000), Fields: fields(4, 997)}, 2},
+		{"fields at the limit", &discordgo.MessageEmbed{Fields: fields(MaxEmbedFields, 10)}, 1},
+		{"fields over the limit", &discordgo.MessageEmbed{Fields: fields(MaxEmbedFields+1, 10)}, 2},
+		{"field value over the limit", &discordgo.MessageEmbed{Fields: fields(1, MaxFieldValue+1)}, 1},
+	}
+
+	for _, test := range tests {
+		embeds := SplitEmbed(test.embed)
+		if len(embeds) != test.embeds {
+			t.Errorf("%s: expected %d embeds, got %d", test.name, test.embeds, len(embeds))
+			continue
+		}
+		checkEmbeds(t, test.name, embeds)
+	}
+}
+
+func TestSplitEmbedDescriptionLimit(t *testing.T) {
+	// Discord allows descriptions of 4096 characters
+	if embeds := SplitEmbed(&discordgo.MessageEmbed{Description: strings.Repeat("a", 4096)}); len(embeds) != 1 {
+		t.Fatalf("expected a description of 4096 characters to fit, got %d embeds", len(embeds))
+	}
+
+	embeds := SplitEmbed(&discordgo.MessageEmbed{Description: strings.Repeat("a", 4097)})
+	if len(embeds) != 2 || len(embeds[0].Description) > 4096 || len(embeds[1].Description) == 0 {
+		t.Fatalf("expected a description of 4097 characters to be split over 2 embeds, got %d", len(embeds))
+	}
+	checkEmbeds(t, "description limit", embeds)
+}
+
+func TestSplitEmbedKeepsParts(t *testing.T) {
+	em := &discordgo.MessageEmbed{
+		Title:       "Title",
+		Description: strings.Repeat("a", MaxEmbedDescription+1),
+		Footer:      &discordgo.MessageEmbedFooter{Text: "Footer"},
+		Fields:      []*discordgo.MessageEmbedField{{Name: "Long", Value: strings.Repeat("v ", MaxFieldValue)}},
+	}
+
+	embeds := SplitEmbed(em)
+	checkEmbeds(t, "parts", embeds)
+	first, last := embeds[0], embeds[len(embeds)-1]
+	if first.Title != "Title" || first.Footer != nil || last.Footer == nil || last.Footer.Text != "Footer" {
+		t.Fatal("expected the title on the first embed and the footer on the last one")
+	}
+
+	var names []string
+	for _, e := range embeds {
+		for _, f := range e.Fields {
+			names = append(names, f.Name)
+		}
+	}
+	if len(names) < 2 || names[0] != "Long" {
+		t.Fatalf("expected the long value to be split over multiple fields, got %q", names)
+	}
+	for _, name := range names[1:] {
+		if name != zeroWidthSpace {
+			t.Fatalf("expected the long value to continue in fields without a name, got %q", names)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package router
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/auttaja/discordgo"
+)
+
+// StateCache caches the guilds, channels and members that had to be fetched from the REST API
+// because they were missing from the state. Lookups for objects that don't exist are cached as well,
+// and concurrent lookups of the same object share a single REST call.
+// The least recently used objects are dropped when it is full, and gateway events keep it up to date
+type StateCache struct {
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	calls   map[string]*cacheCall
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+// cacheCall is a REST call that is in progress, lookups of the same object wait for it
+type cacheCall struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// NewStateCache returns a new StateCache
+// size        : the maximum amount of objects to cache
+// ttl         : how long a fetched object stays cached
+// negativeTTL : how long it is remembered that an object doesn't exist
+func NewStateCache(size int, ttl, negativeTTL time.Duration) *StateCache {
+	return &StateCache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		calls:       make(map[string]*cacheCall),
+	}
+}
+
+// Guild retrieves a guild from the state, the cache or the REST API, in that order
+func (c *StateCache) Guild(s *discordgo.Session, guildID string) (*discordgo.Guild, error) {
+	if g, err := s.State.Guild(guildID); err == nil {
+		return g, nil
+	}
+
+	v, err := c.get("g:"+guildID, func() (interface{}, error) {
+		return s.Guild(guildID)
+	})
+	g, _ := v.(*discordgo.Guild)
+	return g, err
+}
+
+// Channel retrieves a channel from the state, the cache or the REST API, in that order
+func (c *StateCache) Channel(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	if ch, err := s.State.Channel(channelID); err == nil {
+		return ch, nil
+	}
+
+	v, err := c.get("c:"+channelID, func() (interface{}, error) {
+		return s.Channel(channelID)
+	})
+	ch, _ := v.(*discordgo.Channel)
+	return ch, err
+}
+
+// Member retrieves a member from the state, the cache or the REST API, in that order
+func (c *StateCache) Member(s *discordgo.Session, guildID, userID string) (*discordgo.Member, error) {
+	if m, err := s.State.Member(guildID, userID); err == nil {
+		return m, nil
+	}
+
+	v, err := c.get(memberKey(guildID, userID), func() (interface{}, error) {
+		return s.GuildMember(guildID, userID)
+	})
+	m, _ := v.(*discordgo.Member)
+	return m, err
+}
+
+// AddHandlers adds the event handlers that keep the cache up to date to the session
+func (c *StateCache) AddHandlers(s *discordgo.Session) {
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildUpdate) {
+		c.update("g:"+e.ID, e.Guild)
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildDelete) {
+		c.Remove("g:" + e.ID)
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.ChannelCreate) {
+		c.Remove("c:" + e.ID)
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.ChannelUpdate) {
+		c.update("c:"+e.ID, e.Channel)
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.ChannelDelete) {
+		c.Remove("c:" + e.ID)
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildMemberAdd) {
+		if e.User != nil {
+			c.Remove(memberKey(e.GuildID, e.User.ID))
+		}
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildMemberUpdate) {
+		if e.User != nil {
+			c.update(memberKey(e.GuildID, e.User.ID), e.Member)
+		}
+	})
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.GuildMemberRemove) {
+		if e.User != nil {
+			c.Remove(memberKey(e.GuildID, e.User.ID))
+		}
+	})
+}
+
+// Remove drops an object from the cache, keys are "g:" followed by the guild ID for guilds,
+// "c:" followed by the channel ID for channels and "m:guildID:userID" for members
+func (c *StateCache) Remove(key string) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+	c.mu.Unlock()
+}
+
+// get returns the cached object for the key, or fetches it. Only one fetch per key runs at the same time
+func (c *StateCache) get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return e.value, e.err
+		}
+		c.removeElement(el)
+	}
+
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+
+	call := &cacheCall{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.value, call.err = fetch()
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if call.err == nil {
+		c.add(key, call.value, nil, c.ttl)
+	} else if isNotFound(call.err) {
+		c.add(key, nil, call.err, c.negativeTTL)
+	}
+	c.mu.Unlock()
+	call.wg.Done()
+
+	return call.value, call.err
+}
+
+// update replaces the object if it is cached, objects that aren't cached are left alone
+// so the cache only holds what has been looked up
+func (c *StateCache) update(key string, value interface{}) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+		c.add(key, value, nil, c.ttl)
+	}
+	c.mu.Unlock()
+}
+
+// add caches an object, c.mu must be held
+func (c *StateCache) add(key string, value interface{}, err error, ttl time.Duration) {
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		value:   value,
+		err:     err,
+		expires: time.Now().Add(ttl),
+	})
+
+	for c.size > 0 && c.lru.Len() > c.size {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// removeElement removes an element from the cache, c.mu must be held
+func (c *StateCache) removeElement(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+func memberKey(guildID, userID string) string {
+	return "m:" + guildID + ":" + userID
+}
+
+// isNotFound returns true if the error is a REST error saying the object doesn't exist
+func isNotFound(err error) bool {
+	restErr, ok := err.(*discordgo.RESTError)
+	return ok && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,25 @@
+package router
+
+import "log"
+
+// ToggleStore decides whether a route has been disabled in a guild or channel
+type ToggleStore interface {
+	// IsDisabled returns true if the route may not be ran in the given guild and channel
+	IsDisabled(guildID, channelID string, route *Route) (bool, error)
+}
+
+// IsDisabledIn checks the root's ToggleStore, if any, to see if this route has been disabled in the given guild and channel.
+// Routes are never disabled in DMs
+func (r *Route) IsDisabledIn(guildID, channelID string) bool {
+	root := r.Root()
+	if root.Toggles == nil || guildID == "" {
+		return false
+	}
+
+	disabled, err := root.Toggles.IsDisabled(guildID, channelID, r)
+	if err != nil {
+		log.Println("Unable to check the command toggles, allowing the command: ", err)
+		return false
+	}
+	return disabled
+}
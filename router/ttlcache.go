@@ -0,0 +1,57 @@
+package router
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLCache caches a value per key, like the settings of a guild, and fetches it again once it is older than the ttl
+type TTLCache struct {
+	ttl time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]*ttlEntry
+}
+
+type ttlEntry struct {
+	value   interface{}
+	fetched time.Time
+}
+
+// NewTTLCache returns a new TTLCache
+// ttl : how long a value is cached before it gets fetched again
+func NewTTLCache(ttl time.Duration) *TTLCache {
+	return &TTLCache{
+		ttl:     ttl,
+		entries: make(map[string]*ttlEntry),
+	}
+}
+
+// Get returns the cached value for the key, or calls fetch and caches what it returns if there is none
+// or it expired. Errors returned by fetch are not cached
+func (c *TTLCache) Get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.RLock()
+	cached, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && time.Since(cached.fetched) < c.ttl {
+		return cached.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = &ttlEntry{value: value, fetched: time.Now()}
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+// Invalidate drops the cached value for the key
+func (c *TTLCache) Invalidate(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
@@ -0,0 +1,327 @@
+package tags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxNameLength is the maximum length of the name of a tag
+const maxNameLength = 32
+
+// Register adds the tag commands to the router and makes the tags of a guild usable
+// as if they were commands, for commands that don't exist
+func Register(r *router.Route, s *Store) {
+	r.Fallback = s.fallback
+
+	tag := r.On("tag", s.showCommand).
+		Alias("tags").
+		Desc("Shows a tag, the text of a tag may use placeholders like {user}, {channel}, {guild} and {args}").
+		Usage("tag <name> [arguments]")
+	tag.On("create", s.createCommand).
+		Alias("add").
+		Desc("Creates a tag").
+		Usage("tag create <name> <text>")
+	tag.On("embed", s.embedCommand).
+		Desc("Creates a tag showing an embed, or sets the embed of an existing tag").
+		Usage("tag embed <name> <title> | <description>")
+	tag.On("edit", s.editCommand).
+		Desc("Changes the text of a tag, only its owner and people that can manage messages can do this").
+		Usage("tag edit <name> <text>")
+	tag.On("delete", s.deleteCommand).
+		Alias("remove").
+		Desc("Deletes a tag, only its owner and people that can manage messages can do this").
+		Usage("tag delete <name>")
+	tag.On("list", s.listCommand).
+		Desc("Lists the tags of this server").
+		Usage("tag list")
+	tag.On("info", s.infoCommand).
+		Desc("Shows who owns a tag and how often it has been used").
+		Usage("tag info <name>")
+}
+
+func (s *Store) fallback(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrCouldNotFindRoute
+	}
+
+	t, err := s.Get(ctx.Msg.GuildID, ctx.Args[0])
+	if err == mongo.ErrNoDocuments {
+		return router.ErrCouldNotFindRoute
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.show(ctx, t, ctx.Args[1:])
+}
+
+func (s *Store) show(ctx *router.Context, t *Tag, args router.Args) error {
+	data := DataFromContext(ctx, args)
+	// {user.mention} should still mention the user using the tag, but nobody else
+	ctx.AllowedMentions = &router.AllowedMentions{Users: []string{ctx.Msg.Author.ID}}
+
+	var embed *discordgo.MessageEmbed
+	if t.Embed != nil {
+		embed = t.Embed.Render(data)
+	}
+
+	_, err := ctx.SendMessage(Render(t.Content, data), embed, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		_ = s.Used(t.GuildID, t.Name)
+	}()
+	return nil
+}
+
+func (s *Store) showCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+	if len(ctx.Args) < 2 {
+		return router.ErrInvalidArgument
+	}
+
+	t, err := s.Get(ctx.Msg.GuildID, ctx.Args[1])
+	if err != nil {
+		return err
+	}
+
+	return s.show(ctx, t, ctx.Args[2:])
+}
+
+func (s *Store) createCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+	if len(ctx.Args) < 3 {
+		return router.ErrInvalidArgument
+	}
+
+	name := ctx.Args[1]
+	if problem := s.checkName(ctx, name); problem != "" {
+		_, err := ctx.Reply(problem)
+		return err
+	}
+
+	err := s.Save(&Tag{
+		GuildID:   ctx.Msg.GuildID,
+		Name:      name,
+		Content:   ctx.Args.After(2),
+		OwnerID:   ctx.Msg.Author.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Created the tag `%s`", name))
+	return err
+}
+
+func (s *Store) embedCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+	if len(ctx.Args) < 3 {
+		return router.ErrInvalidArgument
+	}
+
+	name := ctx.Args[1]
+	parts := strings.SplitN(ctx.Args.After(2), "|", 2)
+	embed := &Embed{Title: strings.TrimSpace(parts[0])}
+	if len(parts) > 1 {
+		embed.Description = strings.TrimSpace(parts[1])
+	}
+
+	t, err := s.Get(ctx.Msg.GuildID, name)
+	switch err {
+	case nil:
+		ok, err := s.canManage(ctx, t)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return router.ErrUserNoPermissions
+		}
+		// the cached tag is shared, so change a copy
+		edited := *t
+		t = &edited
+	case mongo.ErrNoDocuments:
+		if problem := s.checkName(ctx, name); problem != "" {
+			_, err = ctx.Reply(problem)
+			return err
+		}
+		t = &Tag{
+			GuildID:   ctx.Msg.GuildID,
+			Name:      name,
+			OwnerID:   ctx.Msg.Author.ID,
+			CreatedAt: time.Now(),
+		}
+	default:
+		return err
+	}
+
+	t.Embed = embed
+	err = s.Save(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Set the embed of the tag `%s`", name))
+	return err
+}
+
+func (s *Store) editCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+	if len(ctx.Args) < 3 {
+		return router.ErrInvalidArgument
+	}
+
+	t, err := s.Get(ctx.Msg.GuildID, ctx.Args[1])
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.canManage(ctx, t)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return router.ErrUserNoPermissions
+	}
+
+	// the cached tag is shared, so change a copy
+	edited := *t
+	edited.Content = ctx.Args.After(2)
+	err = s.Save(&edited)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Edited the tag `%s`", t.Name))
+	return err
+}
+
+func (s *Store) deleteCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+	if len(ctx.Args) < 2 {
+		return router.ErrInvalidArgument
+	}
+
+	t, err := s.Get(ctx.Msg.GuildID, ctx.Args[1])
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.canManage(ctx, t)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return router.ErrUserNoPermissions
+	}
+
+	err = s.Delete(t.GuildID, t.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Deleted the tag `%s`", t.Name))
+	return err
+}
+
+func (s *Store) listCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+
+	tags, err := s.Tags(ctx.Msg.GuildID)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		_, err = ctx.Reply("This server has no tags")
+		return err
+	}
+
+	var names []string
+	for name := range tags {
+		names = append(names, fmt.Sprintf("`%s`", name))
+	}
+	sort.Strings(names)
+
+	_, err = ctx.ReplyEmbed(
+		discordgo.NewEmbed().
+			SetTitle("Tags").
+			SetDescription(strings.Join(names, ", ")),
+	)
+	return err
+}
+
+func (s *Store) infoCommand(ctx *router.Context) error {
+	if ctx.Msg.GuildID == "" {
+		return router.ErrNotAGuild
+	}
+	if len(ctx.Args) < 2 {
+		return router.ErrInvalidArgument
+	}
+
+	t, err := s.Get(ctx.Msg.GuildID, ctx.Args[1])
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.ReplyEmbed(
+		discordgo.NewEmbed().
+			SetTitle(t.Name).
+			AddField("Owner", fmt.Sprintf("<@%s>", t.OwnerID), true).
+			AddField("Uses", fmt.Sprint(t.Uses), true).
+			AddField("Created", t.CreatedAt.Format("2006-01-02"), true),
+	)
+	return err
+}
+
+// checkName returns why the name can't be used for a new tag, or an empty string if it can
+func (s *Store) checkName(ctx *router.Context, name string) string {
+	if len(name) > maxNameLength {
+		return fmt.Sprintf("The name of a tag can't be longer than %d characters", maxNameLength)
+	}
+
+	root := ctx.Route.Root()
+	if root.Find(name) != nil {
+		return fmt.Sprintf("`%s` is already a command", name)
+	}
+	if tag := root.Find("tag"); tag != nil && tag.Find(name) != nil {
+		return fmt.Sprintf("`%s` can't be used as the name of a tag", name)
+	}
+
+	tags, err := s.Tags(ctx.Msg.GuildID)
+	if err == nil {
+		if _, ok := tags[name]; ok {
+			return fmt.Sprintf("The tag `%s` already exists", name)
+		}
+	}
+	return ""
+}
+
+// canManage returns true if the author owns the tag or can manage messages
+func (s *Store) canManage(ctx *router.Context, t *Tag) (bool, error) {
+	if t.OwnerID == ctx.Msg.Author.ID {
+		return true, nil
+	}
+	return ctx.AuthorHasPermission(discordgo.PermissionManageMessages)
+}
@@ -0,0 +1,143 @@
+package tags
+
+import (
+	"context"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Tag is a piece of text, or an embed, that a guild stored to be shown by name
+type Tag struct {
+	GuildID   string    `bson:"guild_id"`
+	Name      string    `bson:"name"`
+	Content   string    `bson:"content"`
+	Embed     *Embed    `bson:"embed,omitempty"`
+	OwnerID   string    `bson:"owner_id"`
+	Uses      int       `bson:"uses"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+// Embed is the embed a tag shows, the title and description may contain placeholders
+type Embed struct {
+	Title       string `bson:"title"`
+	Description string `bson:"description"`
+	Color       int    `bson:"color"`
+	ImageURL    string `bson:"image_url,omitempty"`
+}
+
+// Render renders the embed with the placeholders replaced by the data
+func (e *Embed) Render(data *Data) *discordgo.MessageEmbed {
+	em := discordgo.NewEmbed().
+		SetTitle(Render(e.Title, data)).
+		SetDescription(Render(e.Description, data)).
+		SetColor(discordgo.Color(e.Color))
+	if e.ImageURL != "" {
+		em.Image = &discordgo.MessageEmbedImage{URL: e.ImageURL}
+	}
+	return em
+}
+
+// Store keeps the tags in MongoDB and caches them per guild
+type Store struct {
+	collection *mongo.Collection
+	cache      *router.TTLCache
+}
+
+// NewStore returns a new Store
+// collection : the collection to keep the tags in
+// ttl        : how long the tags of a guild are cached before they get fetched again
+func NewStore(collection *mongo.Collection, ttl time.Duration) *Store {
+	return &Store{
+		collection: collection,
+		cache:      router.NewTTLCache(ttl),
+	}
+}
+
+// Tags returns all the tags of a guild by name, from the cache if possible
+func (s *Store) Tags(guildID string) (map[string]*Tag, error) {
+	tags, err := s.cache.Get(guildID, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cursor, err := s.collection.Find(ctx, bson.M{"guild_id": guildID})
+		if err != nil {
+			return nil, err
+		}
+
+		var list []*Tag
+		err = cursor.All(ctx, &list)
+		if err != nil {
+			return nil, err
+		}
+
+		tags := make(map[string]*Tag, len(list))
+		for _, t := range list {
+			tags[t.Name] = t
+		}
+		return tags, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags.(map[string]*Tag), nil
+}
+
+// Get returns the tag with the given name, or mongo.ErrNoDocuments if the guild has no such tag
+func (s *Store) Get(guildID, name string) (*Tag, error) {
+	tags, err := s.Tags(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := tags[name]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return t, nil
+}
+
+// Save creates or replaces a tag
+func (s *Store) Save(t *Tag) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"guild_id": t.GuildID, "name": t.Name}
+	_, err := s.collection.ReplaceOne(ctx, filter, t, options.Replace().SetUpsert(true))
+	s.Invalidate(t.GuildID)
+	return err
+}
+
+// Delete removes a tag, it returns mongo.ErrNoDocuments if the guild has no such tag
+func (s *Store) Delete(guildID, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.collection.DeleteOne(ctx, bson.M{"guild_id": guildID, "name": name})
+	s.Invalidate(guildID)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Used increments the use counter of a tag, the cached tag is not updated
+func (s *Store) Used(guildID, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.collection.UpdateOne(ctx, bson.M{"guild_id": guildID, "name": name}, bson.M{"$inc": bson.M{"uses": 1}})
+	return err
+}
+
+// Invalidate drops the cached tags of a guild
+func (s *Store) Invalidate(guildID string) {
+	s.cache.Invalidate(guildID)
+}
@@ -0,0 +1,118 @@
+package tags
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{([a-z]+)(?:\.([a-z0-9]+))?\}`)
+
+// Data is what the placeholders in a tag get replaced with, any of the fields may be nil
+type Data struct {
+	User    *discordgo.User
+	Member  *discordgo.Member
+	Channel *discordgo.Channel
+	Guild   *discordgo.Guild
+	Args    router.Args
+}
+
+// DataFromContext collects the Data for a tag ran in the given context
+func DataFromContext(ctx *router.Context, args router.Args) *Data {
+	d := &Data{
+		User:    ctx.Author(),
+		Channel: ctx.Channel,
+		Args:    args,
+	}
+
+	if ctx.Msg.GuildID != "" {
+		d.Guild, _ = ctx.GetGuild(ctx.Msg.GuildID)
+		d.Member, _ = ctx.GetMember(ctx.Msg.GuildID, ctx.Msg.Author.ID)
+	}
+
+	return d
+}
+
+// Render replaces the placeholders in text with the data, placeholders that are unknown
+// or have no data are left untouched.
+// The template language is deliberately nothing more than substitution, so a tag can never
+// do anything else than show text. The available placeholders are:
+//
+//	{user} {user.name} {user.nick} {user.tag} {user.mention} {user.id} {user.avatar}
+//	{channel} {channel.name} {channel.mention} {channel.id} {channel.topic}
+//	{guild} {guild.name} {guild.id} {guild.members}
+//	{args} {args.0} {args.1} ...
+func Render(text string, data *Data) string {
+	return placeholderRegex.ReplaceAllStringFunc(text, func(p string) string {
+		match := placeholderRegex.FindStringSubmatch(p)
+		value, ok := data.lookup(match[1], match[2])
+		if !ok {
+			return p
+		}
+		return value
+	})
+}
+
+func (d *Data) lookup(object, field string) (string, bool) {
+	switch object {
+	case "user":
+		if d.User == nil {
+			return "", false
+		}
+		switch field {
+		case "", "name":
+			return d.User.Username, true
+		case "nick":
+			if d.Member != nil && d.Member.Nick != "" {
+				return d.Member.Nick, true
+			}
+			return d.User.Username, true
+		case "tag":
+			return d.User.Username + "#" + d.User.Discriminator, true
+		case "mention":
+			return d.User.Mention(), true
+		case "id":
+			return d.User.ID, true
+		case "avatar":
+			return d.User.AvatarURL(""), true
+		}
+	case "channel":
+		if d.Channel == nil {
+			return "", false
+		}
+		switch field {
+		case "", "name":
+			return d.Channel.Name, true
+		case "mention":
+			return d.Channel.Mention(), true
+		case "id":
+			return d.Channel.ID, true
+		case "topic":
+			return d.Channel.Topic, true
+		}
+	case "guild":
+		if d.Guild == nil {
+			return "", false
+		}
+		switch field {
+		case "", "name":
+			return d.Guild.Name, true
+		case "id":
+			return d.Guild.ID, true
+		case "members":
+			return strconv.Itoa(d.Guild.MemberCount), true
+		}
+	case "args":
+		if field == "" {
+			return d.Args.After(0), true
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return "", false
+		}
+		return d.Args.Get(n), true
+	}
+	return "", false
+}
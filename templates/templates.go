@@ -0,0 +1,440 @@
+// Package templates defines embeds in JSON or YAML, so messages like welcome messages, rules and announcements
+// can be written without code. Templates map onto discordgo.MessageEmbed and, with reaction options bound to
+// named handlers, onto utils.StatefulEmbed. Texts can use the placeholders of the tags package
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/dgframework/tags"
+	"github.com/auttaja/dgframework/utils"
+	"github.com/auttaja/discordgo"
+	"gopkg.in/yaml.v2"
+)
+
+// MaxReactions is the amount of different reactions Discord allows on a message
+const MaxReactions = 20
+
+var (
+	// ErrUnknownFormat gets returned by Load when the file isn't .json, .yaml or .yml
+	ErrUnknownFormat = errors.New("templates have to be .json, .yaml or .yml files")
+	// ErrTrailingData gets returned by ParseJSON when there is more after the template
+	ErrTrailingData = errors.New("there is more data after the template")
+)
+
+// Template describes an embed, the message content sent with it and the reaction options of a stateful embed
+type Template struct {
+	Content     string   `json:"content,omitempty" yaml:"content,omitempty"`
+	Title       string   `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	URL         string   `json:"url,omitempty" yaml:"url,omitempty"`
+	Color       string   `json:"color,omitempty" yaml:"color,omitempty"`
+	Timestamp   string   `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Author      *Author  `json:"author,omitempty" yaml:"author,omitempty"`
+	Footer      *Footer  `json:"footer,omitempty" yaml:"footer,omitempty"`
+	Thumbnail   string   `json:"thumbnail,omitempty" yaml:"thumbnail,omitempty"`
+	Image       string   `json:"image,omitempty" yaml:"image,omitempty"`
+	Fields      []Field  `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Options     []Option `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Author is the author of the embed
+type Author struct {
+	Name    string `json:"name" yaml:"name"`
+	URL     string `json:"url,omitempty" yaml:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty" yaml:"icon_url,omitempty"`
+}
+
+// Footer is the footer of the embed
+type Footer struct {
+	Text    string `json:"text" yaml:"text"`
+	IconURL string `json:"icon_url,omitempty" yaml:"icon_url,omitempty"`
+}
+
+// Field is a field of the embed
+type Field struct {
+	Name   string `json:"name" yaml:"name"`
+	Value  string `json:"value" yaml:"value"`
+	Inline bool   `json:"inline,omitempty" yaml:"inline,omitempty"`
+}
+
+// Option is a reaction of a stateful embed, bound to handlers registered with utils.RegisterHandler
+// and utils.RegisterRemoveHandler. With a name it is shown as a field after the fields of the template,
+// without one it is only a reaction
+type Option struct {
+	Emoji         string `json:"emoji" yaml:"emoji"`
+	Name          string `json:"name,omitempty" yaml:"name,omitempty"`
+	Value         string `json:"value,omitempty" yaml:"value,omitempty"`
+	Inline        bool   `json:"inline,omitempty" yaml:"inline,omitempty"`
+	Handler       string `json:"handler,omitempty" yaml:"handler,omitempty"`
+	RemoveHandler string `json:"remove_handler,omitempty" yaml:"remove_handler,omitempty"`
+	Toggle        bool   `json:"toggle,omitempty" yaml:"toggle,omitempty"`
+}
+
+// LimitError gets returned when a rendered text is longer than Discord allows
+type LimitError struct {
+	// Part is the part of the embed that is too long, like "title" or "field 2 value"
+	Part   string
+	Length int
+	Limit  int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("the %s is %d characters long, the limit is %d", e.Part, e.Length, e.Limit)
+}
+
+// ParseJSON parses a template written in JSON and validates it, unknown keys are an error like they are in YAML
+func ParseJSON(data []byte) (*Template, error) {
+	t := &Template{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(t)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, ErrTrailingData
+	}
+	if err = t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseYAML parses a template written in YAML and validates it
+func ParseYAML(data []byte) (*Template, error) {
+	t := &Template{}
+	err := yaml.UnmarshalStrict(data, t)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Load reads a template from a .json, .yaml or .yml file
+func Load(path string) (*Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseJSON(data)
+	case ".yaml", ".yml":
+		return ParseYAML(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Validate checks everything that doesn't depend on the placeholders: the color, timestamp, emojis and handlers,
+// the amount of fields and reactions, and that fields have a name and value. The lengths are checked when rendering
+func (t *Template) Validate() error {
+	if _, err := parseColor(t.Color); err != nil {
+		return err
+	}
+	if t.Timestamp != "" && t.Timestamp != "now" {
+		if _, err := time.Parse(time.RFC3339, t.Timestamp); err != nil {
+			return fmt.Errorf("the timestamp has to be \"now\" or an RFC 3339 time: %v", err)
+		}
+	}
+
+	for i, f := range t.Fields {
+		if strings.TrimSpace(f.Name) == "" || strings.TrimSpace(f.Value) == "" {
+			return fmt.Errorf("field %d needs a name and a value", i+1)
+		}
+	}
+
+	fields := len(t.Fields)
+	seen := make(map[string]bool, len(t.Options))
+	for i, o := range t.Options {
+		emoji, err := parseEmoji(o.Emoji)
+		if err != nil {
+			return fmt.Errorf("option %d: %v", i+1, err)
+		}
+		if seen[emoji.APIName()] {
+			return fmt.Errorf("option %d: the emoji %s is used more than once", i+1, o.Emoji)
+		}
+		seen[emoji.APIName()] = true
+
+		if o.Toggle && o.Name == "" {
+			return fmt.Errorf("option %d is a toggle, so it needs a name", i+1)
+		}
+		if o.Handler == "" && !o.Toggle {
+			return fmt.Errorf("option %d needs a handler, unless it is a toggle", i+1)
+		}
+		if o.Handler != "" && !utils.HasHandler(o.Handler) {
+			return fmt.Errorf("option %d: no handler is registered as %q", i+1, o.Handler)
+		}
+		if o.RemoveHandler != "" && !utils.HasRemoveHandler(o.RemoveHandler) {
+			return fmt.Errorf("option %d: no remove handler is registered as %q", i+1, o.RemoveHandler)
+		}
+
+		if o.Name != "" {
+			if strings.TrimSpace(o.Value) == "" {
+				return fmt.Errorf("option %d has a name, so it needs a value too", i+1)
+			}
+			fields++
+		}
+	}
+
+	if fields > router.MaxEmbedFields {
+		return &LimitError{Part: "amount of fields", Length: fields, Limit: router.MaxEmbedFields}
+	}
+	if len(t.Options) > MaxReactions {
+		return &LimitError{Part: "amount of reactions", Length: len(t.Options), Limit: MaxReactions}
+	}
+	return nil
+}
+
+// Embed renders the template into an embed, replacing the placeholders with the data, which may be nil.
+// It returns a LimitError when the result is too long for Discord. The options are left out
+func (t *Template) Embed(data *tags.Data) (*discordgo.MessageEmbed, error) {
+	em, err := t.embed(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateEmbed(em); err != nil {
+		return nil, err
+	}
+	return em, nil
+}
+
+// Send renders the template and sends the content and embed to the target. The options are left out
+func (t *Template) Send(target discordgo.Messageable, data *tags.Data) (*discordgo.Message, error) {
+	em, err := t.Embed(data)
+	if err != nil {
+		return nil, err
+	}
+
+	content := render(t.Content, data)
+	if n := utf8.RuneCountInString(content); n > router.MaxMessageLength {
+		return nil, &LimitError{Part: "content", Length: n, Limit: router.MaxMessageLength}
+	}
+	return target.SendMessage(content, em, nil)
+}
+
+// Stateful renders the template into a StatefulEmbed of the session, with the options bound to their
+// named handlers so the session can be saved. The content isn't used, as sessions only show embeds
+func (t *Template) Stateful(s *utils.EmbedSession, data *tags.Data) (*utils.StatefulEmbed, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	em, err := t.embed(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the fields of the options are checked on a copy, so nothing is added to the session when they don't fit
+	type option struct {
+		Option
+		emoji *discordgo.Emoji
+	}
+	options := make([]option, 0, len(t.Options))
+	check := *em
+	check.Fields = append([]*discordgo.MessageEmbedField(nil), em.Fields...)
+	for _, o := range t.Options {
+		emoji, _ := parseEmoji(o.Emoji)
+		o.Name, o.Value = render(o.Name, data), render(o.Value, data)
+		options = append(options, option{Option: o, emoji: emoji})
+		if o.Name != "" {
+			check.Fields = append(check.Fields, &discordgo.MessageEmbedField{Name: fmt.Sprintf("%s %s", emoji, o.Name), Value: o.Value})
+		}
+	}
+	if err = validateEmbed(&check); err != nil {
+		return nil, err
+	}
+
+	stateful := utils.NewStatefulEmbed(s)
+	stateful.MessageEmbed = em
+	for _, o := range options {
+		emoji, name, value := o.emoji, o.Name, o.Value
+
+		switch {
+		case o.Toggle:
+			stateful.AddNamedToggle(name, value, o.Inline, emoji, o.Handler, o.RemoveHandler)
+		case name != "":
+			stateful.AddNamedField(name, value, o.Inline, emoji, o.Handler)
+		default:
+			stateful.AddNamedReaction(emoji, o.Handler)
+		}
+		if !o.Toggle && o.RemoveHandler != "" {
+			stateful.OnNamedRemove(emoji, o.RemoveHandler)
+		}
+	}
+
+	return stateful, nil
+}
+
+// embed builds the embed without checking the lengths
+func (t *Template) embed(data *tags.Data) (*discordgo.MessageEmbed, error) {
+	color, err := parseColor(t.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	em := &discordgo.MessageEmbed{
+		Title:       render(t.Title, data),
+		Description: render(t.Description, data),
+		URL:         render(t.URL, data),
+		Color:       discordgo.Color(color),
+	}
+
+	switch t.Timestamp {
+	case "":
+	case "now":
+		em.Timestamp = time.Now().Format(time.RFC3339)
+	default:
+		em.Timestamp = t.Timestamp
+	}
+
+	if t.Author != nil {
+		em.Author = &discordgo.MessageEmbedAuthor{
+			Name:    render(t.Author.Name, data),
+			URL:     render(t.Author.URL, data),
+			IconURL: render(t.Author.IconURL, data),
+		}
+	}
+	if t.Footer != nil {
+		em.Footer = &discordgo.MessageEmbedFooter{
+			Text:    render(t.Footer.Text, data),
+			IconURL: render(t.Footer.IconURL, data),
+		}
+	}
+	if t.Thumbnail != "" {
+		em.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: render(t.Thumbnail, data)}
+	}
+	if t.Image != "" {
+		em.Image = &discordgo.MessageEmbedImage{URL: render(t.Image, data)}
+	}
+
+	for _, f := range t.Fields {
+		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
+			Name:   render(f.Name, data),
+			Value:  render(f.Value, data),
+			Inline: f.Inline,
+		})
+	}
+
+	return em, nil
+}
+
+// render replaces the placeholders in the text, if there is data
+func render(text string, data *tags.Data) string {
+	if data == nil || text == "" {
+		return text
+	}
+	return tags.Render(text, data)
+}
+
+// validateEmbed checks the embed against Discord's limits, counting characters like Discord does
+func validateEmbed(em *discordgo.MessageEmbed) error {
+	check := func(part, text string, limit int) error {
+		if n := utf8.RuneCountInString(text); n > limit {
+			return &LimitError{Part: part, Length: n, Limit: limit}
+		}
+		return nil
+	}
+
+	total := utf8.RuneCountInString(em.Title) + utf8.RuneCountInString(em.Description)
+	if err := check("title", em.Title, router.MaxEmbedTitle); err != nil {
+		return err
+	}
+	if err := check("description", em.Description, router.MaxEmbedDescription); err != nil {
+		return err
+	}
+
+	if len(em.Fields) > router.MaxEmbedFields {
+		return &LimitError{Part: "amount of fields", Length: len(em.Fields), Limit: router.MaxEmbedFields}
+	}
+	for i, f := range em.Fields {
+		if strings.TrimSpace(f.Name) == "" || strings.TrimSpace(f.Value) == "" {
+			return fmt.Errorf("field %d has an empty name or value once the placeholders are replaced", i+1)
+		}
+		if err := check(fmt.Sprintf("field %d name", i+1), f.Name, router.MaxFieldName); err != nil {
+			return err
+		}
+		if err := check(fmt.Sprintf("field %d value", i+1), f.Value, router.MaxFieldValue); err != nil {
+			return err
+		}
+		total += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+
+	if em.Footer != nil {
+		if err := check("footer", em.Footer.Text, router.MaxFooterText); err != nil {
+			return err
+		}
+		total += utf8.RuneCountInString(em.Footer.Text)
+	}
+	if em.Author != nil {
+		if err := check("author name", em.Author.Name, router.MaxAuthorName); err != nil {
+			return err
+		}
+		total += utf8.RuneCountInString(em.Author.Name)
+	}
+
+	if total > router.MaxEmbedLength {
+		return &LimitError{Part: "embed", Length: total, Limit: router.MaxEmbedLength}
+	}
+	return nil
+}
+
+// parseColor parses a color written as #rrggbb, 0xrrggbb or a decimal number, an empty color is 0
+func parseColor(color string) (int, error) {
+	digits := strings.TrimSpace(color)
+	if digits == "" {
+		return 0, nil
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(digits, "#"):
+		digits, base = digits[1:], 16
+	case strings.HasPrefix(strings.ToLower(digits), "0x"):
+		digits, base = digits[2:], 16
+	}
+
+	n, err := strconv.ParseInt(digits, base, 32)
+	if err != nil || n < 0 || n > 0xFFFFFF {
+		return 0, fmt.Errorf("%q is not a color, use #rrggbb", color)
+	}
+	return int(n), nil
+}
+
+// parseEmoji parses a unicode emoji or a custom emoji written as <:name:id>, <a:name:id> or name:id
+func parseEmoji(emoji string) (*discordgo.Emoji, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" {
+		return nil, errors.New("the emoji is missing")
+	}
+
+	custom := strings.TrimSuffix(strings.TrimPrefix(emoji, "<"), ">")
+	parts := strings.Split(custom, ":")
+	switch len(parts) {
+	case 1:
+		return &discordgo.Emoji{Name: emoji}, nil
+	case 2:
+		if parts[0] != "" && parts[1] != "" {
+			return &discordgo.Emoji{Name: parts[0], ID: parts[1]}, nil
+		}
+	case 3:
+		if (parts[0] == "" || parts[0] == "a") && parts[1] != "" && parts[2] != "" {
+			return &discordgo.Emoji{Name: parts[1], ID: parts[2], Animated: parts[0] == "a"}, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not an emoji", emoji)
+}
@@ -0,0 +1,78 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/auttaja/dgframework/tags"
+	"github.com/auttaja/discordgo"
+)
+
+const welcome = `
+title: Welcome to {guild.name}
+description: Hi {user.mention}, please read the rules
+color: "#5865F2"
+footer:
+  text: Member {guild.members}
+fields:
+  - name: Rules
+    value: Be nice
+options:
+  - emoji: "<:accept:1234>"
+    name: Accept
+    value: Accept the rules
+    handler: utils.closeEmbed
+`
+
+func TestParseYAML(t *testing.T) {
+	tmpl, err := ParseYAML([]byte(welcome))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	em, err := tmpl.Embed(&tags.Data{
+		User:  &discordgo.User{ID: "1", Username: "user"},
+		Guild: &discordgo.Guild{Name: "Gophers", MemberCount: 42},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if em.Title != "Welcome to Gophers" || em.Description != "Hi <@1>, please read the rules" || em.Color != 0x5865F2 || em.Footer.Text != "Member 42" {
+		t.Fatalf("wrong embed: %q %q %x %q", em.Title, em.Description, em.Color, em.Footer.Text)
+	}
+	if len(em.Fields) != 1 {
+		t.Fatalf("expected the options to be left out, got %d fields", len(em.Fields))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tmpl := range []string{
+		`{"color": "blue"}`,
+		`{"options": [{"emoji": "👍", "handler": "not.registered"}]}`,
+		`{"options": [{"emoji": "👍", "handler": "utils.closeEmbed"}, {"emoji": "👍", "handler": "utils.closeEmbed"}]}`,
+		`{"fields": [{"name": "empty"}]}`,
+		`{"titel": "typo"}`,
+		`{"title": "one"} {"title": "two"}`,
+	} {
+		if _, err := ParseJSON([]byte(tmpl)); err == nil {
+			t.Errorf("expected %s to be invalid", tmpl)
+		}
+	}
+
+	tmpl, err := ParseJSON([]byte(`{"title": "{args}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tmpl.Embed(&tags.Data{Args: []string{strings.Repeat("a", 300)}})
+	if e, ok := err.(*LimitError); !ok || e.Part != "title" || e.Length != 300 {
+		t.Fatalf("expected the title to be too long, got %v", err)
+	}
+
+	tmpl, err = ParseJSON([]byte(`{"fields": [{"name": "Reason", "value": "{args}"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tmpl.Embed(&tags.Data{Args: []string{" "}}); err == nil {
+		t.Fatal("expected a field with an empty value to be invalid")
+	}
+}
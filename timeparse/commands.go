@@ -0,0 +1,90 @@
+package timeparse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+// Register adds the timezone command to the router, which allows users to set their own time zone
+// and server admins to set the default time zone of their server
+func Register(r *router.Route, s *ZoneStore) {
+	tz := r.On("timezone", s.userCommand).
+		Alias("tz").
+		Desc("Shows your time zone, or sets it to the given zone like Europe/Amsterdam. Use \"reset\" to use the server's time zone again").
+		Usage("timezone [zone or reset]")
+
+	tz.On("server", router.RequireGuildPermission(discordgo.PermissionManageServer)(s.guildCommand)).
+		Desc("Sets the default time zone of this server, used for everyone who hasn't set their own. Use \"reset\" to go back to UTC").
+		Usage("timezone server <zone or reset>")
+}
+
+func (s *ZoneStore) userCommand(ctx *router.Context) error {
+	if len(ctx.Args) < 2 {
+		loc, err := s.Location(ctx.Msg.GuildID, ctx.Msg.Author.ID)
+		if err != nil {
+			return err
+		}
+		_, err = ctx.Reply(fmt.Sprintf("Your time zone is `%s`, it is %s there", loc, time.Now().In(loc).Format("15:04 on Monday")))
+		return err
+	}
+
+	name, ok := zoneName(ctx.Args[1])
+	if !ok {
+		_, err := ctx.Reply(fmt.Sprintf("`%s` is not a time zone, use a name like Europe/Amsterdam or America/New_York", ctx.Args[1]))
+		return err
+	}
+
+	err := s.SetUser(ctx.Msg.Author.ID, name)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		_, err = ctx.Reply("Your time zone has been reset")
+		return err
+	}
+	_, err = ctx.Reply(fmt.Sprintf("Your time zone is now `%s`", name))
+	return err
+}
+
+func (s *ZoneStore) guildCommand(ctx *router.Context) error {
+	// the first argument is the full name of the route, "timezone server"
+	if len(ctx.Args) < 2 {
+		return router.ErrInvalidArgument
+	}
+
+	name, ok := zoneName(ctx.Args[1])
+	if !ok {
+		_, err := ctx.Reply(fmt.Sprintf("`%s` is not a time zone, use a name like Europe/Amsterdam or America/New_York", ctx.Args[1]))
+		return err
+	}
+
+	err := s.SetGuild(ctx.Msg.GuildID, name)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		_, err = ctx.Reply("The time zone of this server has been reset to UTC")
+		return err
+	}
+	_, err = ctx.Reply(fmt.Sprintf("The time zone of this server is now `%s`", name))
+	return err
+}
+
+// zoneName validates a time zone given by a user, "reset" returns an empty name
+func zoneName(arg string) (string, bool) {
+	if strings.EqualFold(arg, "reset") {
+		return "", true
+	}
+
+	loc, err := time.LoadLocation(arg)
+	if err != nil || arg == "" || arg == "Local" {
+		return "", false
+	}
+	return loc.String(), true
+}
@@ -0,0 +1,49 @@
+package timeparse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+func TestGuildCommand(t *testing.T) {
+	s := &discordgo.Session{State: discordgo.NewState(), StateEnabled: true}
+	owner := &discordgo.User{ID: "1"}
+	err := s.State.GuildAdd(&discordgo.Guild{
+		ID:       "2",
+		OwnerID:  owner.ID,
+		Members:  []*discordgo.Member{{GuildID: "2", User: owner}},
+		Channels: []*discordgo.Channel{{ID: "3", GuildID: "2"}},
+	}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := router.New()
+	r.Pipes = true
+	Register(r, NewZoneStore(nil, 0))
+
+	// the reply gets piped into a command that keeps it, so nothing is sent and the database isn't needed
+	var reply string
+	r.On("keep", func(ctx *router.Context) error {
+		reply = ctx.Input
+		return nil
+	})
+
+	m := &discordgo.Message{
+		ID:        "4",
+		ChannelID: "3",
+		GuildID:   "2",
+		Author:    owner,
+		Content:   "-timezone server Nowhere/Invalid | -keep",
+		Session:   s,
+	}
+	if err := r.FindAndExecute(s, "-", "5", m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reply, "`Nowhere/Invalid` is not a time zone") {
+		t.Fatalf("expected the zone to be read from the arguments, got %q", reply)
+	}
+}
@@ -0,0 +1,373 @@
+package timeparse
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+)
+
+// maxWords is the maximum amount of arguments FromArgs tries to parse as a time
+const maxWords = 6
+
+var (
+	// ErrUnrecognized gets returned when the input isn't a time or duration that can be parsed
+	ErrUnrecognized = errors.New("unrecognized time")
+
+	durationPartRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]+)`)
+	clockRegex        = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm)?$`)
+	isoDateRegex      = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	slashDateRegex    = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2,4}))?$`)
+	dayNumberRegex    = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+
+	units = map[string]time.Duration{
+		"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+		"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+		"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+		"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	}
+
+	// calendarUnits can't be expressed as a time.Duration, they are added to dates using months
+	calendarUnits = map[string]int{
+		"mo": 1, "month": 1, "months": 1,
+		"y": 12, "yr": 12, "year": 12, "years": 12,
+	}
+
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+
+	monthNames = map[string]time.Month{
+		"january": time.January, "jan": time.January,
+		"february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March,
+		"april": time.April, "apr": time.April,
+		"may":  time.May,
+		"june": time.June, "jun": time.June,
+		"july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sep": time.September, "sept": time.September,
+		"october": time.October, "oct": time.October,
+		"november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+)
+
+// ParseDuration parses durations like "2h30m", "3 days", "1 week and 2 days" or "90 minutes".
+// Months and years are not supported as they don't have a fixed length, use Parse for those
+func ParseDuration(input string) (time.Duration, error) {
+	total, months, err := parseRelative(normalize(input))
+	if err != nil {
+		return 0, err
+	}
+	if months != 0 {
+		return 0, ErrUnrecognized
+	}
+	return total, nil
+}
+
+// Parse parses a point in time relative to now, in the location of now. It understands
+//
+//	durations         : "in 3 days", "2h30m", "in 2 months"
+//	days              : "today", "tonight", "tomorrow", "friday", "next friday", "2019-12-25", "25/12", "dec 25th"
+//	clock times       : "9am", "9:30 pm", "21:00", "noon", "midnight"
+//	days with a clock : "tomorrow 9am", "next friday at 17:00", "dec 25 noon"
+//
+// A day without a clock time keeps the current clock time, and a clock time without a day is the next time it will be that time
+func Parse(input string, now time.Time) (time.Time, error) {
+	s := normalize(input)
+	if s == "" {
+		return time.Time{}, ErrUnrecognized
+	}
+
+	if t, err := relative(strings.TrimPrefix(s, "in "), now); err == nil {
+		return t, nil
+	}
+
+	words := strings.Fields(s)
+	day, words, dayGiven, err := parseDay(words, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(words) > 0 && words[0] == "at" {
+		words = words[1:]
+	}
+
+	hour, minute, clockGiven := now.Hour(), now.Minute(), false
+	if len(words) > 0 {
+		var ok bool
+		hour, minute, ok = parseClock(strings.Join(words, " "))
+		if !ok {
+			return time.Time{}, ErrUnrecognized
+		}
+		clockGiven = true
+	} else if strings.HasPrefix(s, "tonight") {
+		hour, minute, clockGiven = 20, 0, true
+	}
+
+	if !dayGiven && !clockGiven {
+		return time.Time{}, ErrUnrecognized
+	}
+
+	t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+	if !dayGiven && !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// FromArgs parses a time from the arguments starting at index start, using as many arguments as possible.
+// It returns the time and the amount of arguments that were used, so the arguments after it can be used for
+// something else. For example "remind in 3 days take out the trash"
+func FromArgs(args router.Args, start int, now time.Time) (time.Time, int, error) {
+	for n := maxWords; n > 0; n-- {
+		if start+n > len(args) {
+			continue
+		}
+		t, err := Parse(strings.Join(args[start:start+n], " "), now)
+		if err == nil {
+			return t, n, nil
+		}
+	}
+	return time.Time{}, 0, ErrUnrecognized
+}
+
+// DurationFromArgs parses a duration from the arguments starting at index start, like FromArgs does for times
+func DurationFromArgs(args router.Args, start int) (time.Duration, int, error) {
+	for n := maxWords; n > 0; n-- {
+		if start+n > len(args) {
+			continue
+		}
+		d, err := ParseDuration(strings.Join(args[start:start+n], " "))
+		if err == nil {
+			return d, n, nil
+		}
+	}
+	return 0, 0, ErrUnrecognized
+}
+
+func normalize(input string) string {
+	s := strings.ToLower(strings.TrimSpace(input))
+	s = strings.NewReplacer(",", " ", " and ", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// relative parses a duration that may contain months and years and adds it to now
+func relative(s string, now time.Time) (time.Time, error) {
+	total, months, err := parseRelative(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.AddDate(0, months, 0).Add(total), nil
+}
+
+// parseRelative parses all the "<amount> <unit>" parts of s. It returns the total of the fixed length units
+// and the amount of months in the calendar units, amounts that don't fit are unrecognized
+func parseRelative(s string) (time.Duration, int, error) {
+	if s == "" {
+		return 0, 0, ErrUnrecognized
+	}
+
+	var total time.Duration
+	var months int
+	for s != "" {
+		match := durationPartRegex.FindStringSubmatch(s)
+		if match == nil {
+			return 0, 0, ErrUnrecognized
+		}
+
+		amount, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, 0, ErrUnrecognized
+		}
+
+		if unit, ok := units[match[2]]; ok {
+			d := amount * float64(unit)
+			if d >= float64(math.MaxInt64-total) {
+				return 0, 0, ErrUnrecognized
+			}
+			total += time.Duration(d)
+		} else if n, ok := calendarUnits[match[2]]; ok && amount == math.Trunc(amount) {
+			if amount*float64(n) >= float64(math.MaxInt32-months) {
+				return 0, 0, ErrUnrecognized
+			}
+			months += int(amount) * n
+		} else {
+			return 0, 0, ErrUnrecognized
+		}
+
+		s = strings.TrimSpace(s[len(match[0]):])
+	}
+	return total, months, nil
+}
+
+// parseDay parses the words at the start that describe a day, it returns the day, the words that are left
+// and whether a day was given at all. Dates that don't exist, like 31/02, are unrecognized
+func parseDay(words []string, now time.Time) (time.Time, []string, bool, error) {
+	if len(words) == 0 {
+		return now, words, false, nil
+	}
+
+	first := words[0]
+	switch first {
+	case "today", "tonight":
+		return now, words[1:], true, nil
+	case "tomorrow", "tmrw":
+		return now.AddDate(0, 0, 1), words[1:], true, nil
+	case "on", "this", "next":
+		if len(words) > 1 {
+			if wd, ok := weekdays[words[1]]; ok {
+				return nextWeekday(now, wd), words[2:], true, nil
+			}
+		}
+	}
+
+	if wd, ok := weekdays[first]; ok {
+		return nextWeekday(now, wd), words[1:], true, nil
+	}
+
+	date := func(t time.Time, ok bool, rest []string) (time.Time, []string, bool, error) {
+		if !ok {
+			return time.Time{}, nil, false, ErrUnrecognized
+		}
+		return t, rest, true, nil
+	}
+
+	if match := isoDateRegex.FindStringSubmatch(first); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		day, _ := strconv.Atoi(match[3])
+		t, ok := upcomingDate(now, year, time.Month(month), day, true)
+		return date(t, ok, words[1:])
+	}
+
+	if match := slashDateRegex.FindStringSubmatch(first); match != nil {
+		day, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		year := now.Year()
+		if match[3] != "" {
+			year, _ = strconv.Atoi(match[3])
+			if year < 100 {
+				year += 2000
+			}
+		}
+		t, ok := upcomingDate(now, year, time.Month(month), day, match[3] != "")
+		return date(t, ok, words[1:])
+	}
+
+	// "dec 25" and "25 dec"
+	if len(words) > 1 {
+		if month, ok := monthNames[first]; ok {
+			if match := dayNumberRegex.FindStringSubmatch(words[1]); match != nil {
+				day, _ := strconv.Atoi(match[1])
+				t, ok := upcomingDate(now, now.Year(), month, day, false)
+				return date(t, ok, words[2:])
+			}
+		}
+		if month, ok := monthNames[words[1]]; ok {
+			if match := dayNumberRegex.FindStringSubmatch(first); match != nil {
+				day, _ := strconv.Atoi(match[1])
+				t, ok := upcomingDate(now, now.Year(), month, day, false)
+				return date(t, ok, words[2:])
+			}
+		}
+	}
+
+	return now, words, false, nil
+}
+
+// nextWeekday returns the next day that is the weekday, never today
+func nextWeekday(now time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return now.AddDate(0, 0, days)
+}
+
+// upcomingDate returns the date and whether it exists. Without a year it is the next time the date comes around,
+// which for 29 february can be years away
+func upcomingDate(now time.Time, year int, month time.Month, day int, yearGiven bool) (time.Time, bool) {
+	if month < time.January || month > time.December || day < 1 {
+		return time.Time{}, false
+	}
+	if yearGiven {
+		if day > daysIn(month, year) {
+			return time.Time{}, false
+		}
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location()), true
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// leap days come around at least every 8 years
+	for y := year; y <= year+8; y++ {
+		if day > daysIn(month, y) {
+			continue
+		}
+		if t := time.Date(y, month, day, 0, 0, 0, 0, now.Location()); !t.Before(today) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// daysIn returns the amount of days in the month of the year
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// parseClock parses a clock time like "9am", "9:30 pm", "21:00", "noon" or "midnight"
+func parseClock(s string) (hour, minute int, ok bool) {
+	switch s {
+	case "noon", "midday":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	match := clockRegex.FindStringSubmatch(s)
+	if match == nil {
+		return 0, 0, false
+	}
+
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	if match[3] != "" && (hour < 1 || hour > 12) {
+		return 0, 0, false
+	}
+
+	switch match[3] {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	default:
+		// a bare number like "9" is too ambiguous to be a time
+		if match[2] == "" {
+			return 0, 0, false
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
@@ -0,0 +1,170 @@
+package timeparse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+)
+
+// now is a wednesday morning
+var now = time.Date(2019, time.November, 13, 10, 0, 0, 0, time.UTC)
+
+// newYearsEve is an hour before the end of the year
+var newYearsEve = time.Date(2019, time.December, 31, 23, 0, 0, 0, time.UTC)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		now   time.Time
+		input string
+		want  time.Time
+	}{
+		{now, "in 3 days", date(2019, time.November, 16, 10, 0)},
+		{now, "2h30m", date(2019, time.November, 13, 12, 30)},
+		{now, "in 2 months", date(2020, time.January, 13, 10, 0)},
+		{now, "1 week and 2 days", date(2019, time.November, 22, 10, 0)},
+		{now, "tomorrow 9am", date(2019, time.November, 14, 9, 0)},
+		{now, "9am", date(2019, time.November, 14, 9, 0)},
+		{now, "11pm", date(2019, time.November, 13, 23, 0)},
+		{now, "9:30 PM", date(2019, time.November, 13, 21, 30)},
+		{now, "12am", date(2019, time.November, 14, 0, 0)},
+		{now, "12:30 am", date(2019, time.November, 14, 0, 30)},
+		{now, "12pm", date(2019, time.November, 13, 12, 0)},
+		{now, "noon", date(2019, time.November, 13, 12, 0)},
+		{now, "midnight", date(2019, time.November, 14, 0, 0)},
+		{now, "21:00", date(2019, time.November, 13, 21, 0)},
+		{now, "tonight", date(2019, time.November, 13, 20, 0)},
+		{now, "friday", date(2019, time.November, 15, 10, 0)},
+		{now, "next friday at 17:00", date(2019, time.November, 15, 17, 0)},
+		{now, "wednesday", date(2019, time.November, 20, 10, 0)},
+		{now, "dec 25th noon", date(2019, time.December, 25, 12, 0)},
+		{now, "25 dec", date(2019, time.December, 25, 10, 0)},
+		{now, "2019-12-25", date(2019, time.December, 25, 10, 0)},
+		{now, "25/12/2020", date(2020, time.December, 25, 10, 0)},
+		{now, "5/1", date(2020, time.January, 5, 10, 0)},
+		{now, "29/02", date(2020, time.February, 29, 10, 0)},
+		{newYearsEve, "tomorrow", date(2020, time.January, 1, 23, 0)},
+		{newYearsEve, "1/1", date(2020, time.January, 1, 23, 0)},
+		{newYearsEve, "9am", date(2020, time.January, 1, 9, 0)},
+		{newYearsEve, "31/12", date(2019, time.December, 31, 23, 0)},
+		{newYearsEve, "30 dec", date(2020, time.December, 30, 23, 0)},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.input, test.now)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%q: expected %s, got %s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"banana",
+		"9",
+		"13pm",
+		"0am",
+		"24:00",
+		"9:60",
+		"31/02",
+		"29/02/2019",
+		"25/13",
+		"0/5",
+		"2019-13-45",
+		"2019-02-30",
+		"31 apr",
+		"feb 30",
+		"99999999999 weeks",
+		"99999999999 years",
+		"tomorrow banana",
+	} {
+		if got, err := Parse(input, now); err != ErrUnrecognized {
+			t.Errorf("%q: expected it to be unrecognized, got %s and %v", input, got, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{"2h30m", 2*time.Hour + 30*time.Minute, true},
+		{"3 days", 72 * time.Hour, true},
+		{"1 week and 2 days", 9 * 24 * time.Hour, true},
+		{"90 minutes", 90 * time.Minute, true},
+		{"1.5 hours", 90 * time.Minute, true},
+		{"10s", 10 * time.Second, true},
+		{"2 months", 0, false},
+		{"1 year", 0, false},
+		{"5 parsecs", 0, false},
+		{"99999999999 weeks", 0, false},
+		{"200000 weeks 200000 weeks", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDuration(test.input)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: expected ok to be %v, got %v", test.input, test.ok, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: expected %s, got %s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestFromArgs(t *testing.T) {
+	tests := []struct {
+		args  router.Args
+		start int
+		want  time.Time
+		used  int
+		ok    bool
+	}{
+		{router.Args{"in", "3", "days", "take", "out", "the", "trash"}, 0, date(2019, time.November, 16, 10, 0), 3, true},
+		{router.Args{"remind", "tomorrow", "9am", "call", "mom"}, 1, date(2019, time.November, 14, 9, 0), 2, true},
+		{router.Args{"remind", "next", "friday", "at", "5pm"}, 1, date(2019, time.November, 15, 17, 0), 4, true},
+		{router.Args{"remind", "31/02", "nothing"}, 1, time.Time{}, 0, false},
+		{router.Args{"remind"}, 1, time.Time{}, 0, false},
+	}
+
+	for _, test := range tests {
+		got, used, err := FromArgs(test.args, test.start, now)
+		if (err == nil) != test.ok || used != test.used || !got.Equal(test.want) {
+			t.Errorf("%v: expected %s using %d arguments, got %s using %d (%v)", test.args, test.want, test.used, got, used, err)
+		}
+	}
+}
+
+func TestDurationFromArgs(t *testing.T) {
+	tests := []struct {
+		args  router.Args
+		start int
+		want  time.Duration
+		used  int
+		ok    bool
+	}{
+		{router.Args{"mute", "@user", "2h", "30m", "spamming"}, 2, 2*time.Hour + 30*time.Minute, 2, true},
+		{router.Args{"mute", "@user", "1", "week", "and", "2", "days"}, 2, 9 * 24 * time.Hour, 5, true},
+		{router.Args{"mute", "@user", "spamming"}, 2, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		got, used, err := DurationFromArgs(test.args, test.start)
+		if (err == nil) != test.ok || used != test.used || got != test.want {
+			t.Errorf("%v: expected %s using %d arguments, got %s using %d (%v)", test.args, test.want, test.used, got, used, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package timeparse
+
+import (
+	"context"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// zone is the time zone of a user or guild, the ID is "user:" or "guild:" followed by the ID
+type zone struct {
+	ID   string `bson:"_id"`
+	Zone string `bson:"zone"`
+}
+
+// ZoneStore keeps the time zones of users and guilds in MongoDB and caches them
+type ZoneStore struct {
+	collection *mongo.Collection
+	cache      *router.TTLCache
+}
+
+// NewZoneStore returns a new ZoneStore
+// collection : the collection to keep the time zones in
+// ttl        : how long a time zone is cached before it gets fetched again
+func NewZoneStore(collection *mongo.Collection, ttl time.Duration) *ZoneStore {
+	return &ZoneStore{
+		collection: collection,
+		cache:      router.NewTTLCache(ttl),
+	}
+}
+
+// Location returns the time zone of the user, or of the guild if the user has none, or UTC if neither has one
+func (s *ZoneStore) Location(guildID, userID string) (*time.Location, error) {
+	loc, err := s.get("user:" + userID)
+	if err != nil || loc != nil {
+		return loc, err
+	}
+
+	if guildID != "" {
+		loc, err = s.get("guild:" + guildID)
+		if err != nil || loc != nil {
+			return loc, err
+		}
+	}
+
+	return time.UTC, nil
+}
+
+// Now returns the current time in the time zone of the author of the context
+func (s *ZoneStore) Now(ctx *router.Context) (time.Time, error) {
+	loc, err := s.Location(ctx.Msg.GuildID, ctx.Msg.Author.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
+
+// FromArgs parses a time from the arguments starting at index start, in the time zone of the author of the context.
+// See the FromArgs function
+func (s *ZoneStore) FromArgs(ctx *router.Context, start int) (time.Time, int, error) {
+	now, err := s.Now(ctx)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return FromArgs(ctx.Args, start, now)
+}
+
+// SetUser sets the time zone of a user, an empty zone removes it
+func (s *ZoneStore) SetUser(userID, zone string) error {
+	return s.set("user:"+userID, zone)
+}
+
+// SetGuild sets the default time zone of a guild, an empty zone removes it
+func (s *ZoneStore) SetGuild(guildID, zone string) error {
+	return s.set("guild:"+guildID, zone)
+}
+
+// get returns the time zone with the ID, or nil if there is none
+func (s *ZoneStore) get(id string) (*time.Location, error) {
+	loc, err := s.cache.Get(id, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var z zone
+		err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&z)
+		switch err {
+		case nil:
+			return time.LoadLocation(z.Zone)
+		case mongo.ErrNoDocuments:
+			return (*time.Location)(nil), nil
+		default:
+			return nil, err
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loc.(*time.Location), nil
+}
+
+func (s *ZoneStore) set(id, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if name == "" {
+		_, err = s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	} else {
+		_, err = s.collection.ReplaceOne(ctx, bson.M{"_id": id}, &zone{ID: id, Zone: name}, options.Replace().SetUpsert(true))
+	}
+
+	s.cache.Invalidate(id)
+
+	return err
+}
@@ -0,0 +1,154 @@
+package toggles
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+var channelMention = regexp.MustCompile(`^<#(\d+)>$`)
+
+// Register makes the store the ToggleStore of the router and adds the enable and disable commands to it.
+// Both commands require the Manage Server permission and can't be disabled themselves
+func Register(r *router.Route, s *Store) {
+	r.Toggles = s
+
+	manager := router.RequireGuildPermission(discordgo.PermissionManageServer)
+
+	disable := r.On("disable", manager(s.disableCommand)).
+		Desc("Disables a command or category in this server, or only in the given channel. Lists everything that has been disabled when no command or category is given").
+		Usage("disable [command or category] [#channel]")
+	enable := r.On("enable", manager(s.enableCommand)).
+		Desc("Enables a disabled command or category in this server, or only in the given channel").
+		Usage("enable <command or category> [#channel]")
+
+	s.protected = append(s.protected, disable, enable)
+}
+
+func (s *Store) disableCommand(ctx *router.Context) error {
+	if len(ctx.Args) < 2 {
+		return s.listToggles(ctx)
+	}
+
+	t, err := parseTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.Disable(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Disabled the %s `%s`%s", t.Kind, t.Name, where(t.ChannelID)))
+	return err
+}
+
+func (s *Store) enableCommand(ctx *router.Context) error {
+	if len(ctx.Args) < 2 {
+		return router.ErrInvalidArgument
+	}
+
+	t, err := parseTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.Enable(t.GuildID, t.ChannelID, t.Kind, t.Name)
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		_, err = ctx.Reply(fmt.Sprintf("The %s `%s` was not disabled%s", t.Kind, t.Name, where(t.ChannelID)))
+		return err
+	}
+
+	_, err = ctx.Reply(fmt.Sprintf("Enabled the %s `%s`%s", t.Kind, t.Name, where(t.ChannelID)))
+	return err
+}
+
+func (s *Store) listToggles(ctx *router.Context) error {
+	toggles, err := s.Toggles(ctx.Msg.GuildID)
+	if err != nil {
+		return err
+	}
+
+	if len(toggles) == 0 {
+		_, err = ctx.Reply("Nothing has been disabled in this server")
+		return err
+	}
+
+	var lines []string
+	for _, t := range toggles {
+		lines = append(lines, fmt.Sprintf("%s `%s`%s", t.Kind, t.Name, where(t.ChannelID)))
+	}
+
+	_, err = ctx.ReplyEmbed(
+		discordgo.NewEmbed().
+			SetTitle("Disabled commands and categories").
+			SetDescription(strings.Join(lines, "\n")),
+	)
+	return err
+}
+
+// parseTarget turns the arguments of the enable and disable commands into a Toggle,
+// preferring commands over categories when a name matches both
+func parseTarget(ctx *router.Context) (*Toggle, error) {
+	parts := ctx.Args[1:]
+	t := &Toggle{GuildID: ctx.Msg.GuildID}
+
+	if match := channelMention.FindStringSubmatch(parts[len(parts)-1]); match != nil {
+		ch, err := ctx.GetChannel(match[1])
+		if err != nil {
+			return nil, err
+		}
+		if ch.GuildID != ctx.Msg.GuildID {
+			return nil, router.ErrInvalidArgument
+		}
+		t.ChannelID = ch.ID
+		parts = parts[:len(parts)-1]
+	}
+
+	if len(parts) == 0 {
+		return nil, router.ErrInvalidArgument
+	}
+
+	root := ctx.Route.Root()
+	if rt, depth := root.FindFull(parts...); depth == len(parts) {
+		t.Kind = KindCommand
+		t.Name = rt.FullName()
+		return t, nil
+	}
+
+	if category := findCategory(root, strings.Join(parts, " ")); category != "" {
+		t.Kind = KindCategory
+		t.Name = category
+		return t, nil
+	}
+
+	return nil, router.ErrNotFound
+}
+
+// findCategory searches the route tree for a category with the given name, ignoring case
+func findCategory(r *router.Route, name string) string {
+	for _, rt := range r.Routes {
+		if rt.Category != "" && strings.EqualFold(rt.Category, name) {
+			return rt.Category
+		}
+		if category := findCategory(rt, name); category != "" {
+			return category
+		}
+	}
+	return ""
+}
+
+func where(channelID string) string {
+	if channelID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" in <#%s>", channelID)
+}
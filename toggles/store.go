@@ -0,0 +1,143 @@
+package toggles
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kind is the kind of thing a Toggle disables
+type Kind string
+
+// The kinds of toggles
+const (
+	KindCommand  Kind = "command"
+	KindCategory Kind = "category"
+)
+
+// Toggle is a single command or category that has been disabled in a guild, or in only one channel of it
+type Toggle struct {
+	GuildID string `bson:"guild_id"`
+	// ChannelID is empty if the toggle applies to the whole guild
+	ChannelID string `bson:"channel_id"`
+	Kind      Kind   `bson:"kind"`
+	// Name is the full name of the route or the name of the category
+	Name string `bson:"name"`
+}
+
+// Applies returns true if the toggle disables the route in the given channel
+func (t *Toggle) Applies(channelID string, route *router.Route) bool {
+	if t.ChannelID != "" && t.ChannelID != channelID {
+		return false
+	}
+
+	switch t.Kind {
+	case KindCategory:
+		return strings.EqualFold(t.Name, route.Category)
+	case KindCommand:
+		name := route.FullName()
+		return name == t.Name || strings.HasPrefix(name, t.Name+" ")
+	}
+	return false
+}
+
+// Store is a router.ToggleStore that keeps the toggles in MongoDB and caches them per guild
+type Store struct {
+	collection *mongo.Collection
+	cache      *router.TTLCache
+
+	// protected routes can never be disabled, so nobody can lock themselves out
+	protected []*router.Route
+}
+
+// NewStore returns a new Store
+// collection : the collection to keep the toggles in
+// ttl        : how long the toggles of a guild are cached before they get fetched again
+func NewStore(collection *mongo.Collection, ttl time.Duration) *Store {
+	return &Store{
+		collection: collection,
+		cache:      router.NewTTLCache(ttl),
+	}
+}
+
+// IsDisabled returns true if one of the toggles of the guild applies to the route
+func (s *Store) IsDisabled(guildID, channelID string, route *router.Route) (bool, error) {
+	for _, rt := range s.protected {
+		if rt == route {
+			return false, nil
+		}
+	}
+
+	toggles, err := s.Toggles(guildID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, t := range toggles {
+		if t.Applies(channelID, route) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Toggles returns all the toggles of a guild, from the cache if possible
+func (s *Store) Toggles(guildID string) ([]*Toggle, error) {
+	toggles, err := s.cache.Get(guildID, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cursor, err := s.collection.Find(ctx, bson.M{"guild_id": guildID})
+		if err != nil {
+			return nil, err
+		}
+
+		var toggles []*Toggle
+		err = cursor.All(ctx, &toggles)
+		return toggles, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toggles.([]*Toggle), nil
+}
+
+// Disable stores the toggle
+func (s *Store) Disable(t *Toggle) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"guild_id": t.GuildID, "channel_id": t.ChannelID, "kind": t.Kind, "name": t.Name}
+	_, err := s.collection.UpdateOne(ctx, filter, bson.M{"$set": t}, options.Update().SetUpsert(true))
+	s.Invalidate(t.GuildID)
+	return err
+}
+
+// Enable removes the toggles for the given name, if channelID is empty
+// it removes both the guild wide toggle and the ones for specific channels.
+// It returns the amount of removed toggles
+func (s *Store) Enable(guildID, channelID string, kind Kind, name string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"guild_id": guildID, "kind": kind, "name": name}
+	if channelID != "" {
+		filter["channel_id"] = channelID
+	}
+	res, err := s.collection.DeleteMany(ctx, filter)
+	s.Invalidate(guildID)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// Invalidate drops the cached toggles of a guild
+func (s *Store) Invalidate(guildID string) {
+	s.cache.Invalidate(guildID)
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"github.com/auttaja/discordgo"
+)
+
+// AccessKind is the kind of users an Access allows
+type AccessKind int
+
+// The kinds of access a session or option can have
+const (
+	// AccessOwner only allows the User of the session
+	AccessOwner AccessKind = iota
+	// AccessUsers allows the users in UserIDs
+	AccessUsers
+	// AccessRoles allows the members that have at least one of the roles in RoleIDs
+	AccessRoles
+	// AccessPermission allows the members that have Permission in the channel
+	AccessPermission
+	// AccessEveryone allows everyone
+	AccessEveryone
+)
+
+// Access decides who may use the options of a session. The zero value only allows the owner of the session.
+// It is a plain struct rather than a function so it can be saved with the session
+type Access struct {
+	Kind       AccessKind                 `bson:"kind" json:"kind"`
+	UserIDs    []string                   `bson:"user_ids,omitempty" json:"user_ids,omitempty"`
+	RoleIDs    []string                   `bson:"role_ids,omitempty" json:"role_ids,omitempty"`
+	Permission discordgo.PermissionOffset `bson:"permission,omitempty" json:"permission,omitempty"`
+}
+
+// AllowOwner returns an Access that only allows the User of the session
+func AllowOwner() *Access {
+	return &Access{Kind: AccessOwner}
+}
+
+// AllowUsers returns an Access that allows the given users
+func AllowUsers(userIDs ...string) *Access {
+	return &Access{Kind: AccessUsers, UserIDs: userIDs}
+}
+
+// AllowRoles returns an Access that allows the members with at least one of the given roles
+func AllowRoles(roleIDs ...string) *Access {
+	return &Access{Kind: AccessRoles, RoleIDs: roleIDs}
+}
+
+// AllowPermission returns an Access that allows the members with the permission in the channel of the session
+func AllowPermission(permission discordgo.PermissionOffset) *Access {
+	return &Access{Kind: AccessPermission, Permission: permission}
+}
+
+// AllowEveryone returns an Access that allows everyone
+func AllowEveryone() *Access {
+	return &Access{Kind: AccessEveryone}
+}
+
+// Allows returns true if the user that reacted may use the options of the session
+func (a *Access) Allows(ses *discordgo.Session, session *EmbedSession, r *discordgo.MessageReaction) bool {
+	kind := AccessOwner
+	if a != nil {
+		kind = a.Kind
+	}
+
+	switch kind {
+	case AccessOwner:
+		return session.User != nil && session.User.ID == r.UserID
+	case AccessUsers:
+		return contains(a.UserIDs, r.UserID)
+	case AccessRoles:
+		if r.GuildID == "" {
+			return false
+		}
+		m, err := ses.State.Member(r.GuildID, r.UserID)
+		if err != nil {
+			m, err = ses.GuildMember(r.GuildID, r.UserID)
+			if err != nil {
+				return false
+			}
+		}
+		for _, role := range m.Roles {
+			if contains(a.RoleIDs, role) {
+				return true
+			}
+		}
+		return false
+	case AccessPermission:
+		if r.GuildID == "" {
+			return false
+		}
+		ch, err := ses.State.Channel(r.ChannelID)
+		if err != nil {
+			ch, err = ses.Channel(r.ChannelID)
+			if err != nil {
+				return false
+			}
+		}
+		m, err := ses.State.Member(r.GuildID, r.UserID)
+		if err != nil {
+			m, err = ses.GuildMember(r.GuildID, r.UserID)
+			if err != nil {
+				return false
+			}
+		}
+		perms, err := ch.PermissionsFor(m)
+		if err != nil {
+			return false
+		}
+		return perms.Has(a.Permission)
+	case AccessEveryone:
+		return true
+	}
+	return false
+}
+
+// SetAccess sets who may use the options of the session, options can override it with StatefulEmbed.SetAccess
+func (s *EmbedSession) SetAccess(access *Access) *EmbedSession {
+	s.Access = access
+	return s
+}
+
+// SetAccess overrides who may use the options with the emoji, for example to let everyone vote
+// while only the owner of the session can close it
+func (s *StatefulEmbed) SetAccess(emoji *discordgo.Emoji, access *Access) *StatefulEmbed {
+	for _, o := range s.options {
+		if o.Emoji.ApiName == emoji.APIName() {
+			o.access = access
+		}
+	}
+	return s
+}
+
+// allows returns true if the user that reacted may use the option
+func (o *statefulOption) allows(ses *discordgo.Session, session *EmbedSession, r *discordgo.MessageReaction) bool {
+	if o.access != nil {
+		return o.access.Allows(ses, session, r)
+	}
+	return session.Access.Allows(ses, session, r)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
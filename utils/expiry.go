@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/auttaja/discordgo"
+)
+
+// ExpireAction is what happens to the message of a session when it expires, actions can be combined with |
+type ExpireAction int
+
+// The actions that can be taken when a session expires
+const (
+	// ExpireRemoveReactions removes all reactions from the message
+	ExpireRemoveReactions ExpireAction = 1 << iota
+	// ExpireDisable greys out the embed and says in the footer that it expired
+	ExpireDisable
+	// ExpireDelete deletes the message
+	ExpireDelete
+)
+
+var (
+	// DefaultIdleTimeout is the idle timeout new sessions get, 0 meaning they never expire from being idle.
+	// Sessions only expire when they opt in with SetTimeouts, or when this is changed
+	DefaultIdleTimeout time.Duration
+	// DefaultSessionTimeout is the absolute timeout new sessions get, 0 meaning they never expire from age
+	DefaultSessionTimeout time.Duration
+	// DefaultExpireAction is what happens to the message of new sessions when they expire
+	DefaultExpireAction = ExpireRemoveReactions
+)
+
+// sessionEnder is implemented by session data that has to know when its session expires or its message is deleted
+type sessionEnder interface {
+	sessionEnded(s *EmbedSession)
+}
+
+// expiredColor is the color ExpireDisable gives the embed
+const expiredColor = 0x99AAB5
+
+// SetTimeouts sets how long the session may go without a reaction, and how long it lives in total, before it expires.
+// A timeout of 0 disables it
+func (s *EmbedSession) SetTimeouts(idle, total time.Duration) *EmbedSession {
+	s.IdleTimeout = idle
+	s.Timeout = total
+	return s
+}
+
+// SetOnExpire sets what happens to the message when the session expires
+func (s *EmbedSession) SetOnExpire(action ExpireAction) *EmbedSession {
+	s.OnExpire = action
+	return s
+}
+
+// touch marks the session as used, resetting its idle timeout
+func (s *EmbedSession) touch() {
+	s.activityMu.Lock()
+	s.lastUsed = time.Now()
+	s.activityMu.Unlock()
+}
+
+// expired returns true if the session passed one of its timeouts
+func (s *EmbedSession) expired(now time.Time) bool {
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+
+	if s.created.IsZero() {
+		return false
+	}
+	if s.Timeout > 0 && now.Sub(s.created) > s.Timeout {
+		return true
+	}
+	return s.IdleTimeout > 0 && now.Sub(s.lastUsed) > s.IdleTimeout
+}
+
+// Expire ends the session right away: it stops listening for reactions, is removed from the
+// SessionStore and its OnExpire action is applied to the message. That happens in the background
+// once the running handler of the session returns, so handlers can expire their own session
+func (s *EmbedSession) Expire() {
+	if s.message == nil {
+		return
+	}
+
+	sessionsHolder.locker.Lock()
+	if sessionsHolder.sessions[s.message.ID] == s {
+		delete(sessionsHolder.sessions, s.message.ID)
+	}
+	sessionsHolder.locker.Unlock()
+
+	go s.expire()
+}
+
+// expire removes the session from the store and applies the OnExpire action.
+// It holds handlerMu, so it never runs at the same time as a handler of the session
+func (s *EmbedSession) expire() {
+	s.handlerMu.Lock()
+	defer s.handlerMu.Unlock()
+
+	// stop the reactions that are still being added
+	atomic.AddUint32(&s.reactionGen, 1)
+	s.ended()
+
+	if store := sessionStore(); store != nil {
+		if err := store.Delete(s.message.ID); err != nil {
+			log.Println("could not delete embed session", s.message.ID, err)
+		}
+	}
+
+	if s.OnExpire&ExpireDelete != 0 {
+		_ = s.message.Delete()
+		return
+	}
+
+	if s.OnExpire&ExpireDisable != 0 && s.currentState != nil {
+		em := *s.currentState.MessageEmbed
+		em.Color = expiredColor
+		em.Footer = &discordgo.MessageEmbedFooter{Text: "This menu has expired"}
+		_, _ = s.message.Edit(s.message.NewMessageEdit().SetEmbed(&em))
+	}
+
+	if s.OnExpire&(ExpireRemoveReactions|ExpireDisable) != 0 {
+		s.clearReactions()
+	}
+}
+
+// clearReactions stops the reactions that are still being added and removes all reactions from the message.
+// The sync holds reactionMu until it notices, which may need handlerMu, so the removal runs in the background.
+// Without Manage Messages only the reactions of the bot are removed
+func (s *EmbedSession) clearReactions() {
+	atomic.AddUint32(&s.reactionGen, 1)
+	go func() {
+		s.reactionMu.Lock()
+		defer s.reactionMu.Unlock()
+
+		if atomic.LoadInt32(&s.cannotManage) == 0 && s.message.RemoveAllReactions() == nil {
+			s.reactions = nil
+			return
+		}
+		for _, emoji := range s.reactions {
+			_ = s.removeOwnReaction(emoji)
+		}
+		s.reactions = nil
+	}()
+}
+
+// StartJanitor starts a goroutine that expires the sessions which passed their timeouts every interval.
+// Every session expires in its own goroutine, so a session whose handler is still running doesn't hold up the others.
+// Call the returned function to stop it
+func StartJanitor(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				for _, s := range expiredSessions(now) {
+					go s.expire()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// expiredSessions removes the sessions that expired from the holder and returns them
+func expiredSessions(now time.Time) []*EmbedSession {
+	sessionsHolder.locker.Lock()
+	defer sessionsHolder.locker.Unlock()
+
+	var expired []*EmbedSession
+	for id, s := range sessionsHolder.sessions {
+		if s.expired(now) {
+			delete(sessionsHolder.sessions, id)
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,398 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/auttaja/discordgo"
+)
+
+// MenuEmojis are the emojis a Menu shows its options with
+type MenuEmojis int
+
+// The emoji sets of a Menu
+const (
+	// MenuNumbers shows 10 options per page with 1️⃣ to 🔟
+	MenuNumbers MenuEmojis = iota
+	// MenuLetters shows 16 options per page with 🇦 to 🇵, which together with the controls is the
+	// maximum of 20 reactions on a message
+	MenuLetters
+)
+
+var (
+	numberEmojis = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟"}
+	letterEmojis = []string{"🇦", "🇧", "🇨", "🇩", "🇪", "🇫", "🇬", "🇭", "🇮", "🇯", "🇰", "🇱", "🇲", "🇳", "🇴", "🇵"}
+	confirmEmoji = &discordgo.Emoji{Name: "✅"}
+)
+
+var menuHandlers = struct {
+	sync.RWMutex
+	handlers map[string]func(*EmbedSession, *MenuResult)
+}{handlers: make(map[string]func(*EmbedSession, *MenuResult))}
+
+func init() {
+	RegisterSessionData("utils.menu", &Menu{})
+	RegisterHandler("utils.menu.select", menuSelect)
+	RegisterHandler("utils.menu.back", menuMove(-1))
+	RegisterHandler("utils.menu.forward", menuMove(1))
+	RegisterHandler("utils.menu.confirm", menuConfirm)
+	RegisterHandler("utils.menu.cancel", menuCancel)
+}
+
+// RegisterMenuHandler registers a menu handler by name, so a Menu can still deliver its
+// result after its session was restored from the SessionStore
+func RegisterMenuHandler(name string, handler func(*EmbedSession, *MenuResult)) {
+	menuHandlers.Lock()
+	menuHandlers.handlers[name] = handler
+	menuHandlers.Unlock()
+}
+
+// MenuOption is a choice of a Menu
+type MenuOption struct {
+	// Label is the text shown next to the emoji
+	Label string `json:"label"`
+	// Value can be used by the command to identify the option
+	Value string `json:"value,omitempty"`
+}
+
+// MenuResult is what the user picked in a Menu
+type MenuResult struct {
+	// Indexes are the indexes of the selected options, in order
+	Indexes []int
+	// Options are the selected options, in the order of Indexes
+	Options []*MenuOption
+	// UserID is the user that made the selection, empty if the menu didn't end by a reaction
+	UserID string
+	// Cancelled is true when the menu was cancelled, expired or deleted, there is no selection then
+	Cancelled bool
+}
+
+// Menu lets the user pick one or more options by reacting with the emoji in front of them.
+// With Multi set reacting selects and deselects options and the selection is done with ✅,
+// otherwise the first option picked is the selection. Options that don't fit on one page are paged.
+// The result is passed to the handler and sent on the Result channel. It is the CtxData of its session
+// and can be saved with it, the result channel and a handler that isn't named don't survive a restart though
+type Menu struct {
+	// Base is the embed the options are listed on
+	Base *discordgo.MessageEmbed `json:"base"`
+	// Options are the choices
+	Options []*MenuOption `json:"options"`
+	// Emojis is the emoji set the options are shown with
+	Emojis MenuEmojis `json:"emojis"`
+	// Multi allows selecting more than one option
+	Multi bool `json:"multi"`
+	// Page is the index of the current page
+	Page int `json:"page"`
+	// Selected are the indexes of the options that are selected, sorted
+	Selected []int `json:"selected,omitempty"`
+	// HandlerName is the name of a handler registered with RegisterMenuHandler
+	HandlerName string `json:"handler,omitempty"`
+	// Handler is called with the result
+	Handler func(*EmbedSession, *MenuResult) `json:"-"`
+
+	result chan *MenuResult
+	mu     sync.Mutex
+	done   bool
+}
+
+// NewMenu returns a new single select Menu using numbers
+// base : the embed the options are listed on, nil for an empty one
+func NewMenu(base *discordgo.MessageEmbed) *Menu {
+	if base == nil {
+		base = discordgo.NewEmbed()
+	}
+	return &Menu{
+		Base:   base,
+		result: make(chan *MenuResult, 1),
+	}
+}
+
+// AddOption adds a choice
+// label : the text shown next to the emoji
+// value : a value to identify the option with
+func (m *Menu) AddOption(label, value string) *Menu {
+	m.Options = append(m.Options, &MenuOption{Label: label, Value: value})
+	return m
+}
+
+// SetEmojis sets the emoji set the options are shown with
+func (m *Menu) SetEmojis(emojis MenuEmojis) *Menu {
+	m.Emojis = emojis
+	return m
+}
+
+// SetMulti sets whether more than one option can be selected
+func (m *Menu) SetMulti(multi bool) *Menu {
+	m.Multi = multi
+	return m
+}
+
+// OnSelect sets the handler that is called with the result
+func (m *Menu) OnSelect(handler func(*EmbedSession, *MenuResult)) *Menu {
+	m.Handler = handler
+	return m
+}
+
+// OnNamedSelect is like OnSelect, but takes the name of a handler registered with RegisterMenuHandler
+func (m *Menu) OnNamedSelect(handlerName string) *Menu {
+	m.HandlerName = handlerName
+	return m
+}
+
+// Result returns the channel the result is sent on once the menu ends
+func (m *Menu) Result() <-chan *MenuResult {
+	return m.result
+}
+
+// PageCount returns the amount of pages, which is at least 1
+func (m *Menu) PageCount() int {
+	size := len(m.emojis())
+	if count := (len(m.Options) + size - 1) / size; count > 1 {
+		return count
+	}
+	return 1
+}
+
+// Show sends the menu to the target as a session only the user can use
+func (m *Menu) Show(target discordgo.Messageable, user *discordgo.User) (*EmbedSession, error) {
+	s := NewEmbedSession(target, user, m)
+	m.render(s)
+	return s, s.Show()
+}
+
+func (m *Menu) emojis() []string {
+	if m.Emojis == MenuLetters {
+		return letterEmojis
+	}
+	return numberEmojis
+}
+
+// pageOptions returns the index of the first option on the current page and the amount of options on it
+func (m *Menu) pageOptions() (start, count int) {
+	size := len(m.emojis())
+	start = m.Page * size
+	count = len(m.Options) - start
+	if count > size {
+		count = size
+	}
+	if count < 0 {
+		count = 0
+	}
+	return
+}
+
+func (m *Menu) isSelected(index int) bool {
+	i := sort.SearchInts(m.Selected, index)
+	return i < len(m.Selected) && m.Selected[i] == index
+}
+
+// toggle selects the option if it isn't selected and deselects it otherwise
+func (m *Menu) toggle(index int) {
+	i := sort.SearchInts(m.Selected, index)
+	if i < len(m.Selected) && m.Selected[i] == index {
+		m.Selected = append(m.Selected[:i], m.Selected[i+1:]...)
+		return
+	}
+	m.Selected = append(m.Selected, 0)
+	copy(m.Selected[i+1:], m.Selected[i:])
+	m.Selected[i] = index
+}
+
+// embed builds the embed of the current page
+func (m *Menu) embed() *discordgo.MessageEmbed {
+	em := *m.Base
+	emojis := m.emojis()
+	start, count := m.pageOptions()
+
+	lines := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line := fmt.Sprintf("%s %s", emojis[i], m.Options[start+i].Label)
+		if m.isSelected(start + i) {
+			line += " ✅"
+		}
+		lines = append(lines, line)
+	}
+	if em.Description != "" {
+		em.Description += "\n\n"
+	}
+	em.Description += strings.Join(lines, "\n")
+
+	var status []string
+	if pages := m.PageCount(); pages > 1 {
+		status = append(status, fmt.Sprintf("Page %d/%d", m.Page+1, pages))
+	}
+	if m.Multi {
+		status = append(status, fmt.Sprintf("%d selected", len(m.Selected)))
+	}
+	if len(status) > 0 {
+		em.Footer = appendFooter(m.Base.Footer, strings.Join(status, " • "))
+	}
+
+	return &em
+}
+
+// render makes the current page the embed of the session, showing it if the session has a message
+func (m *Menu) render(s *EmbedSession) {
+	em := &StatefulEmbed{
+		Session:      s,
+		MessageEmbed: m.embed(),
+	}
+	s.embeds = []*StatefulEmbed{em}
+
+	emojis := m.emojis()
+	_, count := m.pageOptions()
+	for i := 0; i < count; i++ {
+		em.AddNamedReaction(&discordgo.Emoji{Name: emojis[i]}, "utils.menu.select")
+	}
+	if m.PageCount() > 1 {
+		em.AddNamedReaction(backEmoji, "utils.menu.back")
+		em.AddNamedReaction(forwardEmoji, "utils.menu.forward")
+	}
+	if m.Multi {
+		em.AddNamedReaction(confirmEmoji, "utils.menu.confirm")
+	}
+	em.AddNamedReaction(closeEmoji, "utils.menu.cancel")
+
+	if s.message != nil {
+		_ = em.Show()
+	}
+}
+
+// deliver passes the result to the handler and the channel, only the first result is delivered
+func (m *Menu) deliver(s *EmbedSession, result *MenuResult) bool {
+	m.mu.Lock()
+	if m.done {
+		m.mu.Unlock()
+		return false
+	}
+	m.done = true
+	m.mu.Unlock()
+
+	select {
+	case m.result <- result:
+	default:
+	}
+
+	handler := m.Handler
+	if handler == nil && m.HandlerName != "" {
+		menuHandlers.RLock()
+		handler = menuHandlers.handlers[m.HandlerName]
+		menuHandlers.RUnlock()
+	}
+	if handler != nil {
+		handler(s, result)
+	}
+	return true
+}
+
+// finish delivers the result and stops the session, leaving the message with the final selection
+func (m *Menu) finish(s *EmbedSession, result *MenuResult) {
+	if s.message != nil {
+		sessionsHolder.locker.Lock()
+		if sessionsHolder.sessions[s.message.ID] == s {
+			delete(sessionsHolder.sessions, s.message.ID)
+		}
+		sessionsHolder.locker.Unlock()
+
+		if store := sessionStore(); store != nil {
+			_ = store.Delete(s.message.ID)
+		}
+
+		s.clearReactions()
+	}
+
+	m.deliver(s, result)
+}
+
+// sessionEnded delivers a cancelled result when the session expires or its message is deleted
+func (m *Menu) sessionEnded(s *EmbedSession) {
+	m.deliver(s, &MenuResult{Cancelled: true})
+}
+
+// selection returns the result with the selected options
+func (m *Menu) selection(userID string) *MenuResult {
+	result := &MenuResult{
+		Indexes: append([]int(nil), m.Selected...),
+		UserID:  userID,
+	}
+	for _, i := range result.Indexes {
+		result.Options = append(result.Options, m.Options[i])
+	}
+	return result
+}
+
+// menuSelect selects the option with the emoji, ending single select menus
+func menuSelect(s *EmbedSession, r *discordgo.MessageReactionAdd) {
+	m, ok := s.CtxData.(*Menu)
+	if !ok {
+		return
+	}
+
+	start, count := m.pageOptions()
+	index := -1
+	for i, emoji := range m.emojis()[:count] {
+		if emoji == r.Emoji.APIName() {
+			index = start + i
+			break
+		}
+	}
+	if index < 0 {
+		return
+	}
+
+	if !m.Multi {
+		m.Selected = []int{index}
+		if s.message != nil {
+			_, _ = s.message.Edit(s.message.NewMessageEdit().SetEmbed(m.embed()))
+		}
+		m.finish(s, m.selection(r.UserID))
+		return
+	}
+
+	m.toggle(index)
+	m.render(s)
+}
+
+// menuMove returns a handler that moves the menu by the amount of pages
+func menuMove(pages int) func(*EmbedSession, *discordgo.MessageReactionAdd) {
+	return func(s *EmbedSession, _ *discordgo.MessageReactionAdd) {
+		m, ok := s.CtxData.(*Menu)
+		if !ok {
+			return
+		}
+		page := m.Page + pages
+		if page < 0 || page >= m.PageCount() {
+			return
+		}
+		m.Page = page
+		m.render(s)
+	}
+}
+
+func menuConfirm(s *EmbedSession, r *discordgo.MessageReactionAdd) {
+	if m, ok := s.CtxData.(*Menu); ok {
+		m.finish(s, m.selection(r.UserID))
+	}
+}
+
+func menuCancel(s *EmbedSession, r *discordgo.MessageReactionAdd) {
+	if m, ok := s.CtxData.(*Menu); ok {
+		m.finish(s, &MenuResult{UserID: r.UserID, Cancelled: true})
+	}
+}
+
+// appendFooter returns a copy of the footer with the text added after its own text
+func appendFooter(footer *discordgo.MessageEmbedFooter, text string) *discordgo.MessageEmbedFooter {
+	result := &discordgo.MessageEmbedFooter{}
+	if footer != nil {
+		*result = *footer
+	}
+	if result.Text != "" {
+		result.Text += " • " + text
+	} else {
+		result.Text = text
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/auttaja/discordgo"
+)
+
+func menuReaction(emoji string) *discordgo.MessageReactionAdd {
+	r := testReaction("")
+	r.Emoji = &discordgo.Emoji{Name: emoji}
+	return r
+}
+
+func TestMenuMultiSelect(t *testing.T) {
+	m := NewMenu(nil).SetMulti(true)
+	for i := 0; i < 12; i++ {
+		m.AddOption("option", "")
+	}
+	s := NewEmbedSession(nil, &discordgo.User{ID: "1"}, m)
+	m.render(s)
+
+	if m.PageCount() != 2 || len(s.embeds[0].options) != 14 {
+		t.Fatalf("expected 2 pages with 14 reactions, got %d pages and %d reactions", m.PageCount(), len(s.embeds[0].options))
+	}
+
+	menuSelect(s, menuReaction("2️⃣"))
+	menuSelect(s, menuReaction("3️⃣"))
+	menuSelect(s, menuReaction("3️⃣"))
+	menuMove(1)(s, nil)
+	if len(s.embeds[0].options) != 6 {
+		t.Fatalf("expected 6 reactions on the last page, got %d", len(s.embeds[0].options))
+	}
+	menuSelect(s, menuReaction("1️⃣"))
+	menuSelect(s, menuReaction("5️⃣"))
+	menuConfirm(s, menuReaction(""))
+
+	result := <-m.Result()
+	if result.Cancelled || len(result.Indexes) != 2 || result.Indexes[0] != 1 || result.Indexes[1] != 10 || len(result.Options) != 2 {
+		t.Fatalf("expected options 2 and 11 to be selected, got %v", result.Indexes)
+	}
+}
+
+func TestMenuSingleSelect(t *testing.T) {
+	var results []*MenuResult
+	m := NewMenu(nil).
+		AddOption("a", "a").
+		AddOption("b", "b").
+		OnSelect(func(_ *EmbedSession, r *MenuResult) { results = append(results, r) })
+	s := NewEmbedSession(nil, &discordgo.User{ID: "1"}, m)
+	m.render(s)
+
+	menuSelect(s, menuReaction("2️⃣"))
+	menuSelect(s, menuReaction("1️⃣"))
+	s.ended()
+
+	if len(results) != 1 || results[0].Options[0].Value != "b" || results[0].UserID != "1" {
+		t.Fatalf("expected one result selecting b, got %d results", len(results))
+	}
+}
@@ -0,0 +1,360 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrNoPage gets returned by a PageProvider when the page doesn't exist
+	ErrNoPage = errors.New("page does not exist")
+	// ErrNoMongoClient gets returned when restoring a MongoPageProvider before SetMongoClient was called
+	ErrNoMongoClient = errors.New("no mongo client set to restore the page provider with")
+	// ErrUnknownFormatter gets returned when a MongoPageProvider uses a formatter that wasn't registered
+	ErrUnknownFormatter = errors.New("the document formatter is not registered")
+)
+
+var mongoProviders = &mongoProviderRegistry{
+	formatters: make(map[string]func(bson.Raw) *discordgo.MessageEmbedField),
+}
+
+type mongoProviderRegistry struct {
+	mu         sync.RWMutex
+	client     *mongo.Client
+	formatters map[string]func(bson.Raw) *discordgo.MessageEmbedField
+}
+
+func init() {
+	RegisterSessionData("utils.mongoPages", &MongoPageProvider{})
+}
+
+// Page is a single page loaded by a PageProvider
+type Page struct {
+	Description string
+	Fields      []*discordgo.MessageEmbedField
+}
+
+// PageProvider loads the pages of a Paginator when they are shown, so huge result sets don't have to be
+// loaded up front. To be saved with a session the provider has to be registered with RegisterSessionData
+type PageProvider interface {
+	// Page returns the page with the index, starting at 0, or ErrNoPage if it doesn't exist
+	Page(index int) (*Page, error)
+}
+
+// PageCounter can be implemented by a PageProvider that knows how many pages there are,
+// without it the paginator only finds out it reached the end when ErrNoPage is returned
+type PageCounter interface {
+	PageCount() (int, error)
+}
+
+// SetMongoClient sets the client MongoPageProviders are restored with after a restart
+func SetMongoClient(client *mongo.Client) {
+	mongoProviders.mu.Lock()
+	mongoProviders.client = client
+	mongoProviders.mu.Unlock()
+}
+
+// RegisterDocumentFormatter registers a function turning a document into a field by name, for use by MongoPageProviders
+func RegisterDocumentFormatter(name string, formatter func(bson.Raw) *discordgo.MessageEmbedField) {
+	mongoProviders.mu.Lock()
+	mongoProviders.formatters[name] = formatter
+	mongoProviders.mu.Unlock()
+}
+
+// MongoPageProvider is a PageProvider showing the documents of a collection that match a filter as fields.
+// Pages are queried by range on the sort fields, starting after the last document of the previous page,
+// so documents added or removed while paging don't shift the pages that follow. The documents need to have the sort fields
+type MongoPageProvider struct {
+	collection *mongo.Collection
+	filter     interface{}
+	sort       interface{}
+	pageSize   int
+	formatter  string
+
+	// ends holds the sort values of the last document of the pages loaded so far, by page index
+	mu   sync.Mutex
+	ends [][]bson.RawValue
+}
+
+// sortKey is a field a MongoPageProvider sorts on
+type sortKey struct {
+	name string
+	desc bool
+}
+
+// mongoPageState is the serializable form of a MongoPageProvider
+type mongoPageState struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+	Filter     string `json:"filter"`
+	Sort       string `json:"sort,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Formatter  string `json:"formatter"`
+}
+
+// NewMongoPageProvider returns a new MongoPageProvider
+// collection : the collection to show the documents of
+// filter     : the filter the documents have to match, nil for all documents
+// sort       : the order to show the documents in, nil for the order of _id. Ties are broken by _id
+// pageSize   : the amount of documents per page, at most router.MaxEmbedFields
+// formatter  : the name of a formatter registered with RegisterDocumentFormatter
+func NewMongoPageProvider(collection *mongo.Collection, filter, sort interface{}, pageSize int, formatter string) *MongoPageProvider {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > router.MaxEmbedFields {
+		pageSize = router.MaxEmbedFields
+	}
+	return &MongoPageProvider{
+		collection: collection,
+		filter:     filter,
+		sort:       sort,
+		pageSize:   pageSize,
+		formatter:  formatter,
+	}
+}
+
+// Page returns the page with the index. Pages before it that weren't loaded yet are walked
+// to find where it starts, only fetching their sort fields
+func (m *MongoPageProvider) Page(index int) (*Page, error) {
+	mongoProviders.mu.RLock()
+	format, ok := mongoProviders.formatters[m.formatter]
+	mongoProviders.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownFormatter
+	}
+
+	keys, sort, err := m.sortKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for len(m.ends) < index {
+		projection := bson.D{}
+		for _, k := range keys {
+			projection = append(projection, bson.E{Key: k.name, Value: 1})
+		}
+
+		end, n, err := m.find(ctx, keys, sort, len(m.ends), options.Find().SetProjection(projection), nil)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrNoPage
+		}
+		m.ends = append(m.ends, end)
+	}
+
+	page := &Page{}
+	end, n, err := m.find(ctx, keys, sort, index, options.Find(), func(doc bson.Raw) {
+		if field := format(doc); field != nil {
+			page.Fields = append(page.Fields, field)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 && index > 0 {
+		return nil, ErrNoPage
+	}
+	if n > 0 && len(m.ends) == index {
+		m.ends = append(m.ends, end)
+	}
+	return page, nil
+}
+
+// find queries the documents of the page with the index, which has to be 0 or follow a page in m.ends.
+// It calls fn with every document and returns the sort values of the last one and the amount of documents
+func (m *MongoPageProvider) find(ctx context.Context, keys []sortKey, sort bson.D, index int, opts *options.FindOptions, fn func(bson.Raw)) ([]bson.RawValue, int, error) {
+	filter := m.filter
+	if index > 0 {
+		filter = bson.D{{Key: "$and", Value: bson.A{m.filter, afterFilter(keys, m.ends[index-1])}}}
+	}
+
+	cursor, err := m.collection.Find(ctx, filter, opts.SetSort(sort).SetLimit(int64(m.pageSize)))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var last bson.Raw
+	n := 0
+	for cursor.Next(ctx) {
+		n++
+		last = cursor.Current
+		if fn != nil {
+			fn(cursor.Current)
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+	if n == 0 {
+		return nil, 0, nil
+	}
+
+	values := make([]bson.RawValue, len(keys))
+	for i, k := range keys {
+		value, err := last.LookupErr(strings.Split(k.name, ".")...)
+		if err != nil {
+			value = bson.RawValue{Type: bsontype.Null}
+		}
+		// the cursor reuses its buffer, so the value has to be copied
+		value.Value = append([]byte(nil), value.Value...)
+		values[i] = value
+	}
+	return values, n, nil
+}
+
+// sortKeys returns the fields the provider sorts on, ending with _id, and the sort document for them
+func (m *MongoPageProvider) sortKeys() ([]sortKey, bson.D, error) {
+	var sort bson.D
+	if m.sort != nil {
+		data, err := bson.Marshal(m.sort)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = bson.Unmarshal(data, &sort)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var keys []sortKey
+	hasID := false
+	for _, e := range sort {
+		var direction float64
+		switch v := e.Value.(type) {
+		case int32:
+			direction = float64(v)
+		case int64:
+			direction = float64(v)
+		case float64:
+			direction = v
+		}
+		keys = append(keys, sortKey{name: e.Key, desc: direction < 0})
+		hasID = hasID || e.Key == "_id"
+	}
+	if !hasID {
+		keys = append(keys, sortKey{name: "_id"})
+		sort = append(sort, bson.E{Key: "_id", Value: 1})
+	}
+	return keys, sort, nil
+}
+
+// afterFilter returns a filter matching the documents that come after the values in the order of the keys
+func afterFilter(keys []sortKey, values []bson.RawValue) bson.D {
+	or := make(bson.A, len(keys))
+	for i, k := range keys {
+		cond := bson.D{}
+		for j := 0; j < i; j++ {
+			cond = append(cond, bson.E{Key: keys[j].name, Value: values[j]})
+		}
+
+		op := "$gt"
+		if k.desc {
+			op = "$lt"
+		}
+		or[i] = append(cond, bson.E{Key: k.name, Value: bson.D{{Key: op, Value: values[i]}}})
+	}
+	return bson.D{{Key: "$or", Value: or}}
+}
+
+// PageCount returns the amount of pages, counting the matching documents
+func (m *MongoPageProvider) PageCount() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := m.collection.CountDocuments(ctx, m.filter)
+	if err != nil {
+		return 0, err
+	}
+	pages := (int(count) + m.pageSize - 1) / m.pageSize
+	if pages == 0 {
+		pages = 1
+	}
+	return pages, nil
+}
+
+// MarshalJSON encodes the provider with the names of its database, collection and formatter, so it can be saved
+func (m *MongoPageProvider) MarshalJSON() ([]byte, error) {
+	state := mongoPageState{
+		Database:   m.collection.Database().Name(),
+		Collection: m.collection.Name(),
+		PageSize:   m.pageSize,
+		Formatter:  m.formatter,
+	}
+
+	filter, err := bson.MarshalExtJSON(m.filter, true, false)
+	if err != nil {
+		return nil, err
+	}
+	state.Filter = string(filter)
+
+	if m.sort != nil {
+		sort, err := bson.MarshalExtJSON(m.sort, true, false)
+		if err != nil {
+			return nil, err
+		}
+		state.Sort = string(sort)
+	}
+
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON decodes a provider encoded by MarshalJSON, using the client set with SetMongoClient
+func (m *MongoPageProvider) UnmarshalJSON(data []byte) error {
+	var state mongoPageState
+	err := json.Unmarshal(data, &state)
+	if err != nil {
+		return err
+	}
+
+	mongoProviders.mu.RLock()
+	client := mongoProviders.client
+	mongoProviders.mu.RUnlock()
+	if client == nil {
+		return ErrNoMongoClient
+	}
+
+	var filter bson.D
+	err = bson.UnmarshalExtJSON([]byte(state.Filter), true, &filter)
+	if err != nil {
+		return err
+	}
+
+	var sort interface{}
+	if state.Sort != "" {
+		var d bson.D
+		err = bson.UnmarshalExtJSON([]byte(state.Sort), true, &d)
+		if err != nil {
+			return err
+		}
+		sort = d
+	}
+
+	p := NewMongoPageProvider(client.Database(state.Database).Collection(state.Collection), filter, sort, state.PageSize, state.Formatter)
+	m.collection, m.filter, m.sort, m.pageSize, m.formatter = p.collection, p.filter, p.sort, p.pageSize, p.formatter
+	m.ends = nil
+	return nil
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoPageProviderRange(t *testing.T) {
+	p := NewMongoPageProvider(nil, nil, bson.D{{Key: "score", Value: -1}, {Key: "name", Value: 1}}, 5, "")
+	keys, sort, err := p.sortKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || !keys[0].desc || keys[1].desc || keys[2].name != "_id" || len(sort) != 3 {
+		t.Fatalf("expected score descending, name and _id, got %v", keys)
+	}
+
+	last, err := bson.Marshal(bson.D{{Key: "_id", Value: 7}, {Key: "score", Value: 10}, {Key: "name", Value: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]bson.RawValue, len(keys))
+	for i, k := range keys {
+		values[i] = bson.Raw(last).Lookup(k.name)
+	}
+
+	data, err := bson.MarshalExtJSON(afterFilter(keys, values), false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"$or":[{"score":{"$lt":10}},{"score":10,"name":{"$gt":"b"}},{"score":10,"name":"b","_id":{"$gt":7}}]}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s", want, data)
+	}
+
+	keys, _, err = NewMongoPageProvider(nil, nil, nil, 5, "").sortKeys()
+	if err != nil || len(keys) != 1 || keys[0].name != "_id" {
+		t.Fatalf("expected to sort on _id without a sort, got %v, %v", keys, err)
+	}
+}
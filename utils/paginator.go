@@ -0,0 +1,424 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/auttaja/dgframework/events"
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+// PaginatorControls are the optional navigation reactions of a Paginator, they can be combined with |.
+// Back and forward are always there
+type PaginatorControls int
+
+// The optional controls of a Paginator
+const (
+	// ControlFirstLast adds reactions to go to the first and the last page
+	ControlFirstLast PaginatorControls = 1 << iota
+	// ControlJump adds a reaction that asks which page to go to
+	ControlJump
+	// ControlClose adds a reaction that deletes the message
+	ControlClose
+)
+
+// DefaultPageSize is the amount of fields a Paginator shows per page by default
+const DefaultPageSize = 8
+
+// pageCacheWindow is how many pages before and after the current one a Paginator keeps when they come from a Provider
+const pageCacheWindow = 5
+
+// ErrTooManyFields gets returned when a page together with the fields of the base embed has more fields than Discord allows
+var ErrTooManyFields = errors.New("the page has more fields than an embed can have")
+
+// JumpTimeout is how long a Paginator waits for the page number after the jump reaction
+var JumpTimeout = 30 * time.Second
+
+// The emojis of the paginator controls
+var (
+	firstEmoji   = &discordgo.Emoji{Name: "⏪"}
+	backEmoji    = &discordgo.Emoji{Name: "⬅"}
+	forwardEmoji = &discordgo.Emoji{Name: "➡"}
+	lastEmoji    = &discordgo.Emoji{Name: "⏩"}
+	jumpEmoji    = &discordgo.Emoji{Name: "🔢"}
+	closeEmoji   = &discordgo.Emoji{Name: "❌"}
+)
+
+// Paginator shows text pages, fields or both, a page at a time. The navigation is done using reactions,
+// so it doesn't take up any fields of the embed. It is the CtxData of its session and can be saved with it.
+// With a Provider the pages are loaded when they are first shown instead of up front
+type Paginator struct {
+	// Base is the embed every page is built on
+	Base *discordgo.MessageEmbed `json:"base"`
+	// Pages are the descriptions of the pages
+	Pages []string `json:"pages,omitempty"`
+	// Fields are shown PageSize at a time, after the fields of Base
+	Fields []*discordgo.MessageEmbedField `json:"fields,omitempty"`
+	// PageSize is the amount of fields per page
+	PageSize int `json:"page_size"`
+	// Page is the index of the current page
+	Page int `json:"page"`
+	// Controls are the optional navigation reactions
+	Controls PaginatorControls `json:"controls"`
+	// Counter shows the page number in the footer
+	Counter bool `json:"counter"`
+	// Provider loads the pages, Pages and Fields are ignored when it is set
+	Provider PageProvider `json:"-"`
+
+	// cache holds the pages loaded from the Provider
+	cache map[int]*Page
+	// total is the amount of pages of the Provider, 0 if it wasn't asked yet and -1 if it is unknown
+	total int
+}
+
+// paginatorJSON has the fields of a Paginator without its methods, so it can be encoded inside MarshalJSON
+type paginatorJSON Paginator
+
+// paginatorState is the serializable form of a Paginator, with the registered name of its provider
+type paginatorState struct {
+	*paginatorJSON
+	ProviderType string          `json:"provider_type,omitempty"`
+	Provider     json.RawMessage `json:"provider,omitempty"`
+}
+
+func init() {
+	RegisterSessionData("utils.paginator", &Paginator{})
+	RegisterHandler("utils.paginator.first", paginatorMove(func(p *Paginator) int { return 0 }))
+	RegisterHandler("utils.paginator.back", paginatorMove(func(p *Paginator) int { return p.Page - 1 }))
+	RegisterHandler("utils.paginator.forward", paginatorMove(func(p *Paginator) int { return p.Page + 1 }))
+	RegisterHandler("utils.paginator.last", paginatorMove(func(p *Paginator) int { return p.PageCount() - 1 }))
+	RegisterHandler("utils.paginator.jump", paginatorJump)
+}
+
+// NewPaginator returns a new Paginator with all controls, a page counter and the DefaultPageSize
+// base : the embed every page is built on, nil for an empty one
+func NewPaginator(base *discordgo.MessageEmbed) *Paginator {
+	if base == nil {
+		base = discordgo.NewEmbed()
+	}
+	return &Paginator{
+		Base:     base,
+		PageSize: DefaultPageSize,
+		Controls: ControlFirstLast | ControlJump | ControlClose,
+		Counter:  true,
+	}
+}
+
+// AddPage adds a page with the text as description
+func (p *Paginator) AddPage(text string) *Paginator {
+	p.Pages = append(p.Pages, text)
+	return p
+}
+
+// AddField adds a field, the fields get spread over the pages
+func (p *Paginator) AddField(name, value string, inline bool) *Paginator {
+	p.Fields = append(p.Fields, &discordgo.MessageEmbedField{Name: name, Value: value, Inline: inline})
+	return p
+}
+
+// SetPageSize sets the amount of fields per page, it is capped at router.MaxEmbedFields minus the fields of Base
+func (p *Paginator) SetPageSize(size int) *Paginator {
+	if max := p.maxPageSize(); size > max {
+		size = max
+	}
+	p.PageSize = size
+	return p
+}
+
+// SetControls sets the optional navigation reactions
+func (p *Paginator) SetControls(controls PaginatorControls) *Paginator {
+	p.Controls = controls
+	return p
+}
+
+// SetCounter sets whether the page number is shown in the footer
+func (p *Paginator) SetCounter(counter bool) *Paginator {
+	p.Counter = counter
+	return p
+}
+
+// SetProvider sets the provider the pages are loaded from when they are shown.
+// To save the paginator with its session, the provider type has to be registered with RegisterSessionData
+func (p *Paginator) SetProvider(provider PageProvider) *Paginator {
+	p.Provider = provider
+	p.cache = nil
+	p.total = 0
+	return p
+}
+
+// PageCount returns the amount of pages, which is at least 1.
+// It returns -1 if the Provider doesn't implement PageCounter or failed to count the pages
+func (p *Paginator) PageCount() int {
+	if p.Provider != nil {
+		if p.total == 0 {
+			p.total = -1
+			if counter, ok := p.Provider.(PageCounter); ok {
+				if n, err := counter.PageCount(); err == nil && n > 0 {
+					p.total = n
+				}
+			}
+		}
+		return p.total
+	}
+
+	count := len(p.Pages)
+	if size := p.pageSize(); len(p.Fields) > 0 {
+		if fieldPages := (len(p.Fields) + size - 1) / size; fieldPages > count {
+			count = fieldPages
+		}
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// Show sends the paginator to the target as a session only the user can use
+func (p *Paginator) Show(target discordgo.Messageable, user *discordgo.User) (*EmbedSession, error) {
+	s := NewEmbedSession(target, user, p)
+	err := p.render(s)
+	if err != nil {
+		return nil, err
+	}
+	return s, s.Show()
+}
+
+// MarshalJSON encodes the paginator with its provider, so it can be saved with its session
+func (p *Paginator) MarshalJSON() ([]byte, error) {
+	state := paginatorState{paginatorJSON: (*paginatorJSON)(p)}
+	if p.Provider != nil {
+		name, data, err := encodeData(p.Provider)
+		if err != nil {
+			return nil, err
+		}
+		state.ProviderType = name
+		state.Provider = data
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON decodes a paginator encoded by MarshalJSON
+func (p *Paginator) UnmarshalJSON(data []byte) error {
+	state := paginatorState{paginatorJSON: (*paginatorJSON)(p)}
+	err := json.Unmarshal(data, &state)
+	if err != nil {
+		return err
+	}
+
+	if state.ProviderType != "" {
+		v, err := decodeData(state.ProviderType, state.Provider)
+		if err != nil {
+			return err
+		}
+		provider, ok := v.(PageProvider)
+		if !ok {
+			return ErrUnknownDataType
+		}
+		p.SetProvider(provider)
+	}
+	return nil
+}
+
+func (p *Paginator) pageSize() int {
+	size := p.PageSize
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	if max := p.maxPageSize(); size > max {
+		size = max
+	}
+	return size
+}
+
+// maxPageSize returns the amount of fields that fit after the fields of Base, at least 1
+func (p *Paginator) maxPageSize() int {
+	max := router.MaxEmbedFields
+	if p.Base != nil {
+		max -= len(p.Base.Fields)
+	}
+	if max < 1 {
+		return 1
+	}
+	return max
+}
+
+// page returns the page with the index from the Provider. The pages around the current one are cached,
+// so going back and forth doesn't load them again
+func (p *Paginator) page(index int) (*Page, error) {
+	if page, ok := p.cache[index]; ok {
+		return page, nil
+	}
+
+	page, err := p.Provider.Page(index)
+	if err != nil {
+		if err == ErrNoPage && p.total == -1 && index > 0 {
+			// Now we know where the pages end
+			p.total = index
+		}
+		return nil, err
+	}
+
+	if p.cache == nil {
+		p.cache = make(map[int]*Page)
+	}
+	for i := range p.cache {
+		if i < index-pageCacheWindow || i > index+pageCacheWindow {
+			delete(p.cache, i)
+		}
+	}
+	p.cache[index] = page
+	return page, nil
+}
+
+// embed builds the embed of the current page
+func (p *Paginator) embed() (*discordgo.MessageEmbed, error) {
+	em := *p.Base
+	em.Fields = append([]*discordgo.MessageEmbedField(nil), p.Base.Fields...)
+
+	if p.Provider != nil {
+		page, err := p.page(p.Page)
+		if err != nil {
+			return nil, err
+		}
+		if page.Description != "" {
+			em.Description = page.Description
+		}
+		if len(em.Fields)+len(page.Fields) > router.MaxEmbedFields {
+			return nil, ErrTooManyFields
+		}
+		em.Fields = append(em.Fields, page.Fields...)
+	} else {
+		if p.Page < len(p.Pages) {
+			em.Description = p.Pages[p.Page]
+		}
+
+		size := p.pageSize()
+		if start := p.Page * size; start < len(p.Fields) {
+			end := start + size
+			if end > len(p.Fields) {
+				end = len(p.Fields)
+			}
+			em.Fields = append(em.Fields, p.Fields[start:end]...)
+		}
+	}
+
+	if p.Counter {
+		counter := fmt.Sprintf("Page %d", p.Page+1)
+		if total := p.PageCount(); total > 0 {
+			counter = fmt.Sprintf("Page %d/%d", p.Page+1, total)
+		}
+		em.Footer = appendFooter(p.Base.Footer, counter)
+	}
+
+	return &em, nil
+}
+
+// render makes the current page the embed of the session, showing it if the session has a message
+func (p *Paginator) render(s *EmbedSession) error {
+	page, err := p.embed()
+	if err != nil {
+		return err
+	}
+
+	em := &StatefulEmbed{
+		Session:      s,
+		MessageEmbed: page,
+	}
+	s.embeds = []*StatefulEmbed{em}
+	// Without a page count there is no last page to go to
+	firstLast := p.Controls&ControlFirstLast != 0 && p.PageCount() > 0
+
+	if firstLast {
+		em.AddNamedReaction(firstEmoji, "utils.paginator.first")
+	}
+	em.AddNamedReaction(backEmoji, "utils.paginator.back")
+	em.AddNamedReaction(forwardEmoji, "utils.paginator.forward")
+	if firstLast {
+		em.AddNamedReaction(lastEmoji, "utils.paginator.last")
+	}
+	if p.Controls&ControlJump != 0 {
+		em.AddNamedReaction(jumpEmoji, "utils.paginator.jump")
+	}
+	if p.Controls&ControlClose != 0 {
+		em.AddNamedReaction(closeEmoji, "utils.closeEmbed")
+	}
+
+	if s.message != nil {
+		_ = em.Show()
+	}
+	return nil
+}
+
+// goTo moves to the page if it exists and shows it, staying on the current page if it can't be loaded
+func (p *Paginator) goTo(s *EmbedSession, page int) {
+	total := p.PageCount()
+	if page < 0 || (total > 0 && page >= total) || page == p.Page {
+		return
+	}
+
+	current := p.Page
+	p.Page = page
+	if p.render(s) != nil {
+		p.Page = current
+	}
+}
+
+// paginatorMove returns a handler that moves to the page returned by page
+func paginatorMove(page func(p *Paginator) int) func(*EmbedSession, *discordgo.MessageReactionAdd) {
+	return func(s *EmbedSession, _ *discordgo.MessageReactionAdd) {
+		if p, ok := s.CtxData.(*Paginator); ok {
+			p.goTo(s, page(p))
+		}
+	}
+}
+
+// paginatorJump asks the user which page to go to and waits for the number in the background,
+// so the other reactions of the session keep working in the meantime
+func paginatorJump(s *EmbedSession, r *discordgo.MessageReactionAdd) {
+	p, ok := s.CtxData.(*Paginator)
+	if !ok || s.message == nil {
+		return
+	}
+
+	total := p.PageCount()
+	text := fmt.Sprintf("<@%s>, which page do you want to go to?", r.UserID)
+	if total > 0 {
+		text += fmt.Sprintf(" (1-%d)", total)
+	}
+	question, err := s.Target.SendMessage(text, nil, nil)
+	if err != nil {
+		return
+	}
+
+	ses := s.message.Session
+	go func() {
+		defer func() { _ = question.Delete() }()
+
+		collector := events.CollectMessages(context.Background(), ses, s.message.ChannelID, events.CollectorOptions{Max: 1, Timeout: JumpTimeout}, func(m *discordgo.Message) bool {
+			if m.Author == nil || m.Author.ID != r.UserID {
+				return false
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(m.Content))
+			return err == nil && n >= 1 && (total <= 0 || n <= total)
+		})
+		answers := collector.Wait()
+		if len(answers) == 0 {
+			return
+		}
+
+		n, _ := strconv.Atoi(strings.TrimSpace(answers[0].Content))
+		_ = ses.ChannelMessageDelete(answers[0].ChannelID, answers[0].ID)
+
+		s.handlerMu.Lock()
+		defer s.handlerMu.Unlock()
+		if s.CtxData == p {
+			p.goTo(s, n-1)
+		}
+	}()
+}
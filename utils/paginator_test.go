@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/auttaja/discordgo"
+)
+
+func TestPaginatorPages(t *testing.T) {
+	base := discordgo.NewEmbed().SetTitle("Warnings")
+	base.Footer = &discordgo.MessageEmbedFooter{Text: "Moderation"}
+
+	p := NewPaginator(base).SetPageSize(3).AddPage("first").AddPage("second")
+	for i := 0; i < 7; i++ {
+		p.AddField("name", "value", false)
+	}
+
+	if p.PageCount() != 3 {
+		t.Fatalf("expected 3 pages, got %d", p.PageCount())
+	}
+
+	em, _ := p.embed()
+	if em.Description != "first" || len(em.Fields) != 3 || em.Footer.Text != "Moderation • Page 1/3" {
+		t.Fatalf("wrong first page: %q, %d fields, footer %q", em.Description, len(em.Fields), em.Footer.Text)
+	}
+
+	p.Page = 2
+	em, _ = p.embed()
+	if em.Description != base.Description || len(em.Fields) != 1 || em.Footer.Text != "Moderation • Page 3/3" {
+		t.Fatalf("wrong last page: %q, %d fields, footer %q", em.Description, len(em.Fields), em.Footer.Text)
+	}
+
+	if len(base.Fields) != 0 || base.Footer.Text != "Moderation" {
+		t.Fatal("building a page changed the base embed")
+	}
+}
+
+func TestPaginatorGoTo(t *testing.T) {
+	p := NewPaginator(nil).AddPage("1").AddPage("2")
+	s := NewEmbedSession(nil, &discordgo.User{ID: "1"}, p)
+
+	p.goTo(s, 5)
+	if p.Page != 0 {
+		t.Fatal("moved to a page that doesn't exist")
+	}
+
+	p.goTo(s, 1)
+	if p.Page != 1 || len(s.embeds) != 1 || len(s.embeds[0].options) != 6 {
+		t.Fatalf("expected page 2 with 6 controls, got page %d and %d controls", p.Page+1, len(s.embeds[0].options))
+	}
+}
+
+type testProvider struct {
+	pages []string
+	loads map[int]int
+}
+
+func (t *testProvider) Page(index int) (*Page, error) {
+	if index >= len(t.pages) {
+		return nil, ErrNoPage
+	}
+	t.loads[index]++
+	return &Page{Description: t.pages[index]}, nil
+}
+
+func TestPaginatorProvider(t *testing.T) {
+	provider := &testProvider{pages: []string{"1", "2"}, loads: make(map[int]int)}
+	p := NewPaginator(nil).SetProvider(provider)
+	s := NewEmbedSession(nil, &discordgo.User{ID: "1"}, p)
+
+	if err := p.render(s); err != nil {
+		t.Fatal(err)
+	}
+	if p.PageCount() != -1 || len(s.embeds[0].options) != 4 {
+		t.Fatalf("expected an unknown page count without first and last, got %d pages and %d controls", p.PageCount(), len(s.embeds[0].options))
+	}
+
+	p.goTo(s, 1)
+	p.goTo(s, 0)
+	p.goTo(s, 1)
+	if p.Page != 1 || s.embeds[0].Description != "2" || provider.loads[0] != 1 || provider.loads[1] != 1 {
+		t.Fatalf("expected every page to be loaded once, got page %d and loads %v", p.Page+1, provider.loads)
+	}
+
+	p.goTo(s, 2)
+	if p.Page != 1 || p.PageCount() != 2 {
+		t.Fatalf("expected to stay on page 2 and learn the page count, got page %d of %d", p.Page+1, p.PageCount())
+	}
+}
+
+func TestPaginatorLimits(t *testing.T) {
+	base := discordgo.NewEmbed().AddField("Total", "40", false)
+	p := NewPaginator(base).SetPageSize(100)
+	if p.PageSize != 24 {
+		t.Fatalf("expected the page size to be capped at 24, got %d", p.PageSize)
+	}
+
+	provider := &testProvider{loads: make(map[int]int)}
+	for i := 0; i < 20; i++ {
+		provider.pages = append(provider.pages, strconv.Itoa(i+1))
+	}
+	p.SetProvider(provider)
+	s := NewEmbedSession(nil, &discordgo.User{ID: "1"}, p)
+	for i := 0; i < 20; i++ {
+		p.goTo(s, i)
+	}
+	if len(p.cache) != pageCacheWindow+1 {
+		t.Fatalf("expected only the pages around the current one to be cached, got %d", len(p.cache))
+	}
+}
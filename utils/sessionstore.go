@@ -0,0 +1,463 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/auttaja/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrSessionNotFound gets returned by a SessionStore when it has no session for the message
+	ErrSessionNotFound = errors.New("embed session not found")
+	// ErrUnknownDataType gets returned when saving a session whose CtxData type wasn't registered with RegisterSessionData
+	ErrUnknownDataType = errors.New("the type of the session data is not registered")
+)
+
+// SessionRetention is how long a MongoSessionStore keeps a session after it was last saved, sessions that
+// never expired or finished would otherwise stay in the collection forever
+var SessionRetention = 30 * 24 * time.Hour
+
+// maxMissingSessions is the amount of messages without a stored session that are remembered
+const maxMissingSessions = 4096
+
+// missingSessions remembers the messages that have no stored session, so reactions on the messages of
+// every guild don't query the store every time
+var missingSessions = newMissingSet(maxMissingSessions)
+
+// missingSet is a set of message IDs that forgets the oldest ID once it is full
+type missingSet struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newMissingSet(size int) *missingSet {
+	return &missingSet{
+		ids:   make(map[string]struct{}, size),
+		order: make([]string, size),
+	}
+}
+
+func (m *missingSet) has(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.ids[id]
+	return ok
+}
+
+func (m *missingSet) add(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ids[id]; ok {
+		return
+	}
+	delete(m.ids, m.order[m.next])
+	m.order[m.next] = id
+	m.next = (m.next + 1) % len(m.order)
+	m.ids[id] = struct{}{}
+}
+
+func (m *missingSet) remove(id string) {
+	m.mu.Lock()
+	delete(m.ids, id)
+	m.mu.Unlock()
+}
+
+func (m *missingSet) clear() {
+	m.mu.Lock()
+	m.ids = make(map[string]struct{}, len(m.order))
+	m.order = make([]string, len(m.order))
+	m.next = 0
+	m.mu.Unlock()
+}
+
+var registry = &sessionRegistry{
+	handlers:  make(map[string]func(*EmbedSession, *discordgo.MessageReactionAdd)),
+	removes:   make(map[string]func(*EmbedSession, *discordgo.MessageReactionRemove)),
+	dataTypes: make(map[string]reflect.Type),
+	dataNames: make(map[reflect.Type]string),
+}
+
+type sessionRegistry struct {
+	mu        sync.RWMutex
+	store     SessionStore
+	handlers  map[string]func(*EmbedSession, *discordgo.MessageReactionAdd)
+	removes   map[string]func(*EmbedSession, *discordgo.MessageReactionRemove)
+	dataTypes map[string]reflect.Type
+	dataNames map[reflect.Type]string
+}
+
+// SessionStore persists embed sessions, so their reactions keep working after the bot restarts
+type SessionStore interface {
+	// Save stores the state of a session, replacing the state it had before
+	Save(state *SessionState) error
+	// Load returns the state of the session on the message, or ErrSessionNotFound
+	Load(messageID string) (*SessionState, error)
+	// Delete removes the session on the message
+	Delete(messageID string) error
+}
+
+// SessionState is the serializable state of an EmbedSession: the embed it currently shows, the options
+// of that embed, its owner and its context data
+type SessionState struct {
+	MessageID string                  `bson:"_id" json:"message_id"`
+	ChannelID string                  `bson:"channel_id" json:"channel_id"`
+	UserID    string                  `bson:"user_id" json:"user_id"`
+	Access    *Access                 `bson:"access,omitempty" json:"access,omitempty"`
+	Embed     *discordgo.MessageEmbed `bson:"embed" json:"embed"`
+	Options   []*OptionState          `bson:"options" json:"options"`
+	DataType  string                  `bson:"data_type,omitempty" json:"data_type,omitempty"`
+	Data      []byte                  `bson:"data,omitempty" json:"data,omitempty"`
+	// Toggled are the users that have each toggle on, by emoji
+	Toggled map[string][]string `bson:"toggled,omitempty" json:"toggled,omitempty"`
+
+	IdleTimeout time.Duration `bson:"idle_timeout" json:"idle_timeout"`
+	Timeout     time.Duration `bson:"timeout" json:"timeout"`
+	OnExpire    ExpireAction  `bson:"on_expire" json:"on_expire"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	LastUsed    time.Time     `bson:"last_used" json:"last_used"`
+	UpdatedAt   time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// OptionState is the serializable state of a reaction option, the handler is stored by the name it was registered with
+type OptionState struct {
+	Name          string  `bson:"name" json:"name"`
+	Value         string  `bson:"value" json:"value"`
+	Emoji         string  `bson:"emoji" json:"emoji"`
+	EmojiDisplay  string  `bson:"emoji_display" json:"emoji_display"`
+	Handler       string  `bson:"handler" json:"handler"`
+	RemoveHandler string  `bson:"remove_handler,omitempty" json:"remove_handler,omitempty"`
+	Toggle        bool    `bson:"toggle,omitempty" json:"toggle,omitempty"`
+	Access        *Access `bson:"access,omitempty" json:"access,omitempty"`
+}
+
+// SetSessionStore sets the store embed sessions are saved to every time they show an embed.
+// Sessions that aren't in memory anymore are loaded from it when a reaction is added to their message
+func SetSessionStore(store SessionStore) {
+	registry.mu.Lock()
+	registry.store = store
+	registry.mu.Unlock()
+	missingSessions.clear()
+}
+
+// RegisterHandler registers a reaction handler by name, so options using it can be restored after a restart.
+// Use AddNamedField and AddNamedReaction to add options with a registered handler
+func RegisterHandler(name string, handler func(*EmbedSession, *discordgo.MessageReactionAdd)) {
+	registry.mu.Lock()
+	registry.handlers[name] = handler
+	registry.mu.Unlock()
+}
+
+// RegisterRemoveHandler registers a reaction remove handler by name, so options using it can be restored after a restart.
+// Use OnNamedRemove and AddNamedToggle to add options with a registered remove handler
+func RegisterRemoveHandler(name string, handler func(*EmbedSession, *discordgo.MessageReactionRemove)) {
+	registry.mu.Lock()
+	registry.removes[name] = handler
+	registry.mu.Unlock()
+}
+
+// RegisterSessionData registers the type of data by name, so a CtxData of that type can be saved as JSON
+// and restored after a restart. Pass a value of the type, like &MyMenuData{}
+func RegisterSessionData(name string, data interface{}) {
+	t := reflect.TypeOf(data)
+	registry.mu.Lock()
+	registry.dataTypes[name] = t
+	registry.dataNames[t] = name
+	registry.mu.Unlock()
+}
+
+func sessionStore() SessionStore {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.store
+}
+
+func namedHandler(name string) func(*EmbedSession, *discordgo.MessageReactionAdd) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.handlers[name]
+}
+
+func namedRemoveHandler(name string) func(*EmbedSession, *discordgo.MessageReactionRemove) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.removes[name]
+}
+
+// HasHandler returns true if a handler was registered with the name using RegisterHandler
+func HasHandler(name string) bool {
+	return namedHandler(name) != nil
+}
+
+// HasRemoveHandler returns true if a handler was registered with the name using RegisterRemoveHandler
+func HasRemoveHandler(name string) bool {
+	return namedRemoveHandler(name) != nil
+}
+
+// State returns the serializable state of the session. Options with a handler that wasn't registered by name
+// are left out, as there is no way to restore them
+func (s *EmbedSession) State() (*SessionState, error) {
+	s.activityMu.Lock()
+	state := &SessionState{
+		IdleTimeout: s.IdleTimeout,
+		Timeout:     s.Timeout,
+		OnExpire:    s.OnExpire,
+		CreatedAt:   s.created,
+		LastUsed:    s.lastUsed,
+		UpdatedAt:   time.Now(),
+	}
+	s.activityMu.Unlock()
+
+	if s.message != nil {
+		state.MessageID = s.message.ID
+		state.ChannelID = s.message.ChannelID
+	}
+	if s.User != nil {
+		state.UserID = s.User.ID
+	}
+	state.Access = s.Access
+	state.Toggled = s.toggledState()
+
+	if s.currentState != nil {
+		state.Embed = s.currentState.MessageEmbed
+		for _, o := range s.currentState.options {
+			if o.handlerName == "" && o.removeHandlerName == "" && !o.toggle {
+				continue
+			}
+			state.Options = append(state.Options, &OptionState{
+				Name:          o.Name,
+				Value:         o.Value,
+				Emoji:         o.Emoji.ApiName,
+				EmojiDisplay:  o.Emoji.DisplayName,
+				Handler:       o.handlerName,
+				RemoveHandler: o.removeHandlerName,
+				Toggle:        o.toggle,
+				Access:        o.access,
+			})
+		}
+	}
+
+	if s.CtxData != nil {
+		name, data, err := encodeData(s.CtxData)
+		if err != nil {
+			return nil, err
+		}
+		state.DataType = name
+		state.Data = data
+	}
+
+	return state, nil
+}
+
+// encodeData encodes a value of a type registered with RegisterSessionData as JSON, returning the name of the type
+func encodeData(v interface{}) (string, []byte, error) {
+	registry.mu.RLock()
+	name, ok := registry.dataNames[reflect.TypeOf(v)]
+	registry.mu.RUnlock()
+	if !ok {
+		return "", nil, ErrUnknownDataType
+	}
+
+	data, err := json.Marshal(v)
+	if marshalErr, ok := err.(*json.MarshalerError); ok {
+		// a MarshalJSON that encodes data of its own, like the provider of a Paginator, may return ErrUnknownDataType
+		err = marshalErr.Err
+	}
+	return name, data, err
+}
+
+// decodeData decodes JSON encoded by encodeData into a value of the type registered with the name
+func decodeData(name string, data []byte) (interface{}, error) {
+	registry.mu.RLock()
+	t, ok := registry.dataTypes[name]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownDataType
+	}
+
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		err := json.Unmarshal(data, v.Interface())
+		return v.Interface(), err
+	}
+
+	v := reflect.New(t)
+	err := json.Unmarshal(data, v.Interface())
+	return v.Elem().Interface(), err
+}
+
+// save saves the session to the store, if there is one. Sessions with data of a type that wasn't registered
+// can't be restored, so they are only kept in memory
+func (s *EmbedSession) save() {
+	store := sessionStore()
+	if store == nil || s.message == nil {
+		return
+	}
+
+	state, err := s.State()
+	if err == ErrUnknownDataType {
+		return
+	}
+	if err == nil {
+		err = store.Save(state)
+	}
+	if err != nil {
+		log.Println("could not save embed session", s.message.ID, err)
+		return
+	}
+	missingSessions.remove(s.message.ID)
+}
+
+// restoreSession loads the session on the message from the store and rebuilds it
+func restoreSession(ses *discordgo.Session, messageID string) (*EmbedSession, error) {
+	store := sessionStore()
+	if store == nil || missingSessions.has(messageID) {
+		return nil, ErrSessionNotFound
+	}
+
+	state, err := store.Load(messageID)
+	if err == ErrSessionNotFound {
+		missingSessions.add(messageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ses.ChannelMessage(state.ChannelID, state.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := ses.State.Channel(state.ChannelID)
+	if err != nil {
+		ch, err = ses.Channel(state.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var data interface{}
+	if state.DataType != "" {
+		data, err = decodeData(state.DataType, state.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := NewEmbedSession(ch, &discordgo.User{ID: state.UserID}, data)
+	s.message = m
+	s.Access = state.Access
+	s.IdleTimeout = state.IdleTimeout
+	s.Timeout = state.Timeout
+	s.OnExpire = state.OnExpire
+	s.created = state.CreatedAt
+	s.lastUsed = state.LastUsed
+	for _, r := range m.Reactions {
+		if r.Me && r.Emoji != nil {
+			s.reactions = append(s.reactions, r.Emoji.APIName())
+		}
+	}
+
+	em := &StatefulEmbed{
+		Session:      s,
+		MessageEmbed: state.Embed,
+	}
+	if em.MessageEmbed == nil {
+		em.MessageEmbed = discordgo.NewEmbed()
+	}
+	for _, o := range state.Options {
+		em.options = append(em.options, &statefulOption{
+			Name:              o.Name,
+			Value:             o.Value,
+			Emoji:             &statefulEmoji{ApiName: o.Emoji, DisplayName: o.EmojiDisplay},
+			handlerName:       o.Handler,
+			removeHandlerName: o.RemoveHandler,
+			toggle:            o.Toggle,
+			access:            o.Access,
+			parent:            em,
+		})
+	}
+	s.embeds = []*StatefulEmbed{em}
+	s.currentState = em
+	for emoji, users := range state.Toggled {
+		for _, userID := range users {
+			s.setToggled(emoji, userID, true)
+		}
+	}
+
+	if s.expired(time.Now()) {
+		s.expire()
+		return nil, ErrSessionNotFound
+	}
+
+	return s, nil
+}
+
+// MongoSessionStore is a SessionStore keeping the sessions in a MongoDB collection
+type MongoSessionStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSessionStore returns a new MongoSessionStore
+// collection : the collection to keep the sessions in
+func NewMongoSessionStore(collection *mongo.Collection) *MongoSessionStore {
+	return &MongoSessionStore{collection: collection}
+}
+
+// EnsureTTLIndex creates the index that makes MongoDB remove the sessions that weren't saved for the retention.
+// Changing the retention of an existing index needs the index "last_used_ttl" to be dropped first
+func (m *MongoSessionStore) EnsureTTLIndex(retention time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"last_used": 1},
+		Options: options.Index().SetName("last_used_ttl").SetExpireAfterSeconds(int32(retention / time.Second)),
+	})
+	return err
+}
+
+// Save stores the state of a session
+func (m *MongoSessionStore) Save(state *SessionState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"_id": state.MessageID}, state, options.Replace().SetUpsert(true))
+	return err
+}
+
+// Load returns the state of the session on the message, or ErrSessionNotFound
+func (m *MongoSessionStore) Load(messageID string) (*SessionState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state := &SessionState{}
+	err := m.collection.FindOne(ctx, bson.M{"_id": messageID}).Decode(state)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Delete removes the session on the message
+func (m *MongoSessionStore) Delete(messageID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.collection.DeleteOne(ctx, bson.M{"_id": messageID})
+	return err
+}
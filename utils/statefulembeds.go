@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/auttaja/discordgo"
 )
@@ -19,6 +22,32 @@ type EmbedSession struct {
 	User         *discordgo.User
 	CtxData      interface{}
 	currentState *StatefulEmbed
+
+	// Access decides who may use the options, nil only allows User
+	Access *Access
+
+	// IdleTimeout is how long the session may go without a reaction before it expires, 0 meaning never
+	IdleTimeout time.Duration
+	// Timeout is how long the session lives in total before it expires, 0 meaning forever
+	Timeout time.Duration
+	// OnExpire is what happens to the message when the session expires
+	OnExpire ExpireAction
+
+	// handlerMu makes the handlers of the session run one at a time
+	handlerMu  sync.Mutex
+	activityMu sync.Mutex
+	created    time.Time
+	lastUsed   time.Time
+
+	// reactions are the emojis the bot reacted with, in order, guarded by reactionMu
+	reactionMu   sync.Mutex
+	reactions    []string
+	reactionGen  uint32
+	cannotManage int32
+
+	// toggled are the users that have each toggle on, by emoji
+	toggleMu sync.Mutex
+	toggled  map[string]map[string]bool
 }
 
 // StatefulEmbed is a wrapper around the discordgo embed and
@@ -35,11 +64,19 @@ type sessions struct {
 }
 
 type statefulOption struct {
-	Name    string
-	Value   string
-	Emoji   *statefulEmoji
-	Handler func(*EmbedSession, *discordgo.MessageReactionAdd)
-	parent  *StatefulEmbed
+	Name        string
+	Value       string
+	Emoji       *statefulEmoji
+	Handler     func(*EmbedSession, *discordgo.MessageReactionAdd)
+	handlerName string
+	access      *Access
+	parent      *StatefulEmbed
+
+	// RemoveHandler is called when a user removes their reaction, options with one keep the reactions of users
+	RemoveHandler     func(*EmbedSession, *discordgo.MessageReactionRemove)
+	removeHandlerName string
+	// toggle options track for every user whether their reaction is on the message
+	toggle bool
 }
 
 type statefulEmoji struct {
@@ -47,6 +84,33 @@ type statefulEmoji struct {
 	DisplayName string
 }
 
+// handle calls the handler of the option, looking it up by name if it was registered with RegisterHandler
+func (o *statefulOption) handle(s *EmbedSession, r *discordgo.MessageReactionAdd) {
+	handler := o.Handler
+	if handler == nil && o.handlerName != "" {
+		handler = namedHandler(o.handlerName)
+	}
+	if handler != nil {
+		handler(s, r)
+	}
+}
+
+// handleRemove calls the remove handler of the option, looking it up by name if it was registered with RegisterRemoveHandler
+func (o *statefulOption) handleRemove(s *EmbedSession, r *discordgo.MessageReactionRemove) {
+	handler := o.RemoveHandler
+	if handler == nil && o.removeHandlerName != "" {
+		handler = namedRemoveHandler(o.removeHandlerName)
+	}
+	if handler != nil {
+		handler(s, r)
+	}
+}
+
+// keepsReaction returns true if the reaction of a user has to stay on the message, so removing it can be handled
+func (o *statefulOption) keepsReaction() bool {
+	return o.toggle || o.RemoveHandler != nil || o.removeHandlerName != ""
+}
+
 func newSessions() *sessions {
 	return &sessions{
 		locker:   &sync.Mutex{},
@@ -57,13 +121,17 @@ func newSessions() *sessions {
 // NewEmbedSession returns a new EmbedSession
 func NewEmbedSession(target discordgo.Messageable, User *discordgo.User, ctx interface{}) *EmbedSession {
 	return &EmbedSession{
-		Target:  target,
-		User:    User,
-		CtxData: ctx,
+		Target:      target,
+		User:        User,
+		CtxData:     ctx,
+		IdleTimeout: DefaultIdleTimeout,
+		Timeout:     DefaultSessionTimeout,
+		OnExpire:    DefaultExpireAction,
 	}
 }
 
-// Show creates the message and replaces the embed with the first StatefulEmbed provided to the session
+// Show creates the message and replaces the embed with the first StatefulEmbed provided to the session.
+// If a SessionStore is set the session gets saved every time it shows an embed
 func (s *EmbedSession) Show() (err error) {
 	em := discordgo.
 		NewEmbed().
@@ -74,6 +142,11 @@ func (s *EmbedSession) Show() (err error) {
 	}
 	s.message = m
 
+	s.activityMu.Lock()
+	s.created = time.Now()
+	s.lastUsed = s.created
+	s.activityMu.Unlock()
+
 	sessionsHolder.locker.Lock()
 	sessionsHolder.sessions[m.ID] = s
 	sessionsHolder.locker.Unlock()
@@ -81,6 +154,18 @@ func (s *EmbedSession) Show() (err error) {
 	return s.embeds[0].Show()
 }
 
+// Message returns the message of the session, nil if it wasn't shown yet
+func (s *EmbedSession) Message() *discordgo.Message {
+	return s.message
+}
+
+// ended tells the CtxData that the session ended without it, if it wants to know
+func (s *EmbedSession) ended() {
+	if e, ok := s.CtxData.(sessionEnder); ok {
+		e.sessionEnded(s)
+	}
+}
+
 func (s *statefulEmoji) react(m *discordgo.Message) error {
 	return m.Session.MessageReactionAdd(m.ChannelID, m.ID, s.ApiName)
 }
@@ -109,18 +194,17 @@ func NewStatefulEmbed(s *EmbedSession) *StatefulEmbed {
 // handler : the handler to call
 func (s *StatefulEmbed) AddField(name, value string, inline bool, emoji *discordgo.Emoji, handler func(*EmbedSession, *discordgo.MessageReactionAdd)) {
 	if emoji != nil && handler != nil {
-		e := &statefulEmoji{
-			DisplayName: emoji.String(),
-			ApiName:     emoji.APIName(),
-		}
-		o := &statefulOption{
-			Name:    name,
-			Value:   value,
-			Emoji:   e,
-			Handler: handler,
-			parent:  s,
-		}
-		s.options = append(s.options, o)
+		s.addOption(name, value, emoji, handler, "")
+		name = fmt.Sprintf("%s %s", emoji, name)
+	}
+	s.MessageEmbed = s.MessageEmbed.AddField(name, value, inline)
+}
+
+// AddNamedField is like AddField, but takes the name of a handler registered with RegisterHandler,
+// so the option still works when the session is restored after a restart
+func (s *StatefulEmbed) AddNamedField(name, value string, inline bool, emoji *discordgo.Emoji, handlerName string) {
+	if emoji != nil && handlerName != "" {
+		s.addOption(name, value, emoji, nil, handlerName)
 		name = fmt.Sprintf("%s %s", emoji, name)
 	}
 	s.MessageEmbed = s.MessageEmbed.AddField(name, value, inline)
@@ -130,68 +214,150 @@ func (s *StatefulEmbed) AddField(name, value string, inline bool, emoji *discord
 // emoji   : the emoji to react with to call the handler
 // handler : the handler to call
 func (s *StatefulEmbed) AddReaction(emoji *discordgo.Emoji, handler func(*EmbedSession, *discordgo.MessageReactionAdd)) {
+	s.addOption("", "", emoji, handler, "")
+}
+
+// AddNamedReaction is like AddReaction, but takes the name of a handler registered with RegisterHandler,
+// so the reaction still works when the session is restored after a restart
+func (s *StatefulEmbed) AddNamedReaction(emoji *discordgo.Emoji, handlerName string) {
+	s.addOption("", "", emoji, nil, handlerName)
+}
+
+func (s *StatefulEmbed) addOption(name, value string, emoji *discordgo.Emoji, handler func(*EmbedSession, *discordgo.MessageReactionAdd), handlerName string) {
 	e := &statefulEmoji{
 		DisplayName: emoji.String(),
 		ApiName:     emoji.APIName(),
 	}
 	o := &statefulOption{
-		Emoji:   e,
-		Handler: handler,
-		parent:  s,
+		Name:        name,
+		Value:       value,
+		Emoji:       e,
+		Handler:     handler,
+		handlerName: handlerName,
+		parent:      s,
 	}
 	s.options = append(s.options, o)
 }
 
-func (s *StatefulEmbed) addReactions() {
-	var embedEdited bool
-	err := s.Session.message.RemoveAllReactions()
-	if err != nil {
-		return
+// syncReactions brings the reactions of the bot on the message in line with the options of the embed,
+// only removing the reactions that aren't needed anymore and adding the ones that are missing.
+// It stops as soon as another embed is shown, leaving the rest to the sync of that embed
+func (s *StatefulEmbed) syncReactions(gen uint32) {
+	session := s.Session
+	session.reactionMu.Lock()
+	defer session.reactionMu.Unlock()
+
+	stale := func() bool {
+		return atomic.LoadUint32(&session.reactionGen) != gen
 	}
 
+	wanted := make(map[string]bool, len(s.options))
 	for _, o := range s.options {
-		err = o.Emoji.react(s.Session.message)
-
-		if err != nil {
-			log.Println(err.(*discordgo.RESTError).Message)
-			if err.(*discordgo.RESTError).Message != nil && err.(*discordgo.RESTError).Message.Code == 10014 {
-				toRemove := -1
-				for i, f := range s.Fields {
-					if f.Name == fmt.Sprintf("%s %s", o.Emoji, o.Name) {
-						toRemove = i
-						break
-					}
-				}
-				if toRemove >= 0 {
-					s.MessageEmbed = s.RemoveField(toRemove)
-					embedEdited = true
-
-					_, _ = s.Session.Target.SendMessage(
-						"",
-						discordgo.NewEmbed().
-							SetDescription("Oops, I did not find at least one of the emojis for this page, please review all items that should have been on here"),
-						nil,
-					)
-				}
-			} else {
-				return
-			}
+		wanted[o.Emoji.ApiName] = true
+	}
+
+	kept := make([]string, 0, len(session.reactions))
+	for i, emoji := range session.reactions {
+		if stale() {
+			session.reactions = append(kept, session.reactions[i:]...)
+			return
+		}
+		if wanted[emoji] || session.removeOwnReaction(emoji) != nil {
+			kept = append(kept, emoji)
+		}
+	}
+	session.reactions = kept
+
+	have := make(map[string]bool, len(kept))
+	for _, emoji := range kept {
+		have[emoji] = true
+	}
+
+	var embedEdited bool
+	for _, o := range s.options {
+		if have[o.Emoji.ApiName] {
+			continue
+		}
+		if stale() {
+			return
+		}
+
+		err := o.Emoji.react(session.message)
+		if err == nil {
+			have[o.Emoji.ApiName] = true
+			session.reactions = append(session.reactions, o.Emoji.ApiName)
+			continue
+		}
+
+		restErr, ok := err.(*discordgo.RESTError)
+		if !ok || restErr.Message == nil || restErr.Message.Code != 10014 {
+			log.Println("could not add reaction to stateful embed", err)
+			return
+		}
+
+		// 10014 is Unknown Emoji, the emoji can't be used so its field is removed
+		if s.removeOptionField(o) {
+			embedEdited = true
 		}
 	}
 
 	if embedEdited {
-		s.Session.currentState = s
-		_, err = s.Session.message.Edit(
-			s.Session.message.
+		session.handlerMu.Lock()
+		defer session.handlerMu.Unlock()
+
+		session.currentState = s
+		_, _ = session.message.Edit(
+			session.message.
 				NewMessageEdit().
 				SetEmbed(s.MessageEmbed),
 		)
 	}
+}
 
-	return
+// removeOptionField removes the field of an option whose emoji can't be used and tells the user about it
+func (s *StatefulEmbed) removeOptionField(o *statefulOption) bool {
+	toRemove := -1
+	for i, f := range s.Fields {
+		if f.Name == fmt.Sprintf("%s %s", o.Emoji, o.Name) {
+			toRemove = i
+			break
+		}
+	}
+	if toRemove < 0 {
+		return false
+	}
+
+	s.MessageEmbed = s.RemoveField(toRemove)
+	_, _ = s.Session.Target.SendMessage(
+		"",
+		discordgo.NewEmbed().
+			SetDescription("Oops, I did not find at least one of the emojis for this page, please review all items that should have been on here"),
+		nil,
+	)
+	return true
+}
+
+// removeOwnReaction removes a reaction of the bot from the message, which doesn't need any permissions
+func (s *EmbedSession) removeOwnReaction(emoji string) error {
+	return s.message.Session.MessageReactionRemove(s.message.ChannelID, s.message.ID, emoji, "@me")
 }
 
-// Show replaces the current embed and reactions in discord
+// removeUserReaction removes the reaction of a user, so they can use the same option again.
+// It needs Manage Messages, once that is missing the session stops trying and the reactions stay
+func (s *EmbedSession) removeUserReaction(r *discordgo.MessageReactionAdd) {
+	if atomic.LoadInt32(&s.cannotManage) == 1 {
+		return
+	}
+
+	err := r.Remove()
+	if restErr, ok := err.(*discordgo.RESTError); ok && restErr.Message != nil && restErr.Message.Code == 50013 {
+		// 50013 is Missing Permissions
+		atomic.StoreInt32(&s.cannotManage, 1)
+	}
+}
+
+// Show replaces the current embed and reactions in discord, only adding and removing the reactions that differ
+// from the embed that was shown before
 func (s *StatefulEmbed) Show() (err error) {
 	s.Session.currentState = s
 	_, err = s.Session.message.Edit(
@@ -203,7 +369,8 @@ func (s *StatefulEmbed) Show() (err error) {
 		return
 	}
 
-	go s.addReactions()
+	s.Session.save()
+	go s.syncReactions(atomic.AddUint32(&s.Session.reactionGen, 1))
 
 	return
 }
@@ -214,30 +381,140 @@ func StatefulReactionHandler(s *discordgo.Session, r *discordgo.MessageReactionA
 		return
 	}
 
-	sessionsHolder.locker.Lock()
-	defer sessionsHolder.locker.Unlock()
-	embedSession, ok := sessionsHolder.sessions[r.MessageID]
-	if !ok {
+	handleReaction(s, r, r.Emoji.APIName())
+}
+
+// handleReaction calls the handlers of the options with the emoji on the session of the message.
+// The global lock is only held for the lookup, the handlers of a session run one at a time while
+// handlers of different sessions run at the same time
+func handleReaction(s *discordgo.Session, r *discordgo.MessageReactionAdd, emoji string) {
+	embedSession := findSession(s, r.MessageID)
+	if embedSession == nil {
 		return
 	}
 
-	if embedSession.User.ID != r.UserID {
-		_ = r.Remove()
+	embedSession.handlerMu.Lock()
+	defer embedSession.handlerMu.Unlock()
+
+	embedSession.touch()
+
+	// reactions on options are removed so they can be used again, unless the option handles their removal.
+	// Other reactions are only removed when the user has no access
+	var matched, remove bool
+	for _, o := range embedSession.currentState.options {
+		if o.Emoji.ApiName != emoji {
+			continue
+		}
+		matched = true
+		if !o.allows(s, embedSession, r.MessageReaction) {
+			remove = true
+			continue
+		}
+
+		if o.toggle {
+			embedSession.setToggled(emoji, r.UserID, true)
+			embedSession.save()
+		}
+		o.handle(embedSession, r)
+		if !o.keepsReaction() {
+			remove = true
+		}
+	}
+
+	if remove || (!matched && !embedSession.Access.Allows(s, embedSession, r.MessageReaction)) {
+		embedSession.removeUserReaction(r)
+	}
+}
+
+// StatefulReactionRemoveHandler is the reaction remove event handler for the stateful embeds,
+// it calls the remove handlers and turns off toggles
+func StatefulReactionRemoveHandler(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	if s.State.MyUser().ID == r.UserID {
 		return
 	}
 
+	handleReactionRemove(s, r, r.Emoji.APIName())
+}
+
+// handleReactionRemove calls the remove handlers of the options with the emoji on the session of the message
+func handleReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove, emoji string) {
+	embedSession := findSession(s, r.MessageID)
+	if embedSession == nil {
+		return
+	}
+
+	embedSession.handlerMu.Lock()
+	defer embedSession.handlerMu.Unlock()
+
+	embedSession.touch()
+
 	for _, o := range embedSession.currentState.options {
-		if o.Emoji.ApiName == r.Emoji.APIName() {
-			o.Handler(embedSession, r)
+		if o.Emoji.ApiName != emoji || !o.keepsReaction() || !o.allows(s, embedSession, r.MessageReaction) {
+			continue
+		}
+
+		if o.toggle {
+			embedSession.setToggled(emoji, r.UserID, false)
+			embedSession.save()
+		}
+		o.handleRemove(embedSession, r)
+	}
+}
+
+// findSession returns the session on the message, restoring it from the SessionStore if it isn't in memory
+func findSession(s *discordgo.Session, messageID string) *EmbedSession {
+	sessionsHolder.locker.Lock()
+	embedSession, ok := sessionsHolder.sessions[messageID]
+	sessionsHolder.locker.Unlock()
+	if ok {
+		return embedSession
+	}
+
+	embedSession, err := restoreSession(s, messageID)
+	if err != nil {
+		if err != ErrSessionNotFound {
+			log.Println("could not restore embed session", messageID, err)
 		}
+		return nil
 	}
+
+	// another reaction may have restored the session in the meantime
+	sessionsHolder.locker.Lock()
+	defer sessionsHolder.locker.Unlock()
+	if existing, ok := sessionsHolder.sessions[messageID]; ok {
+		return existing
+	}
+	sessionsHolder.sessions[messageID] = embedSession
+	return embedSession
 }
 
 // StatefulMessageDelete is the message delete event handler for the stateful embeds
 func StatefulMessageDelete(_ *discordgo.Session, m *discordgo.MessageDelete) {
 	sessionsHolder.locker.Lock()
+	embedSession, ok := sessionsHolder.sessions[m.ID]
 	delete(sessionsHolder.sessions, m.ID)
 	sessionsHolder.locker.Unlock()
+
+	if ok {
+		embedSession.handlerMu.Lock()
+		embedSession.ended()
+		embedSession.handlerMu.Unlock()
+	}
+
+	if store := sessionStore(); store != nil {
+		if err := store.Delete(m.ID); err != nil {
+			log.Println("could not delete embed session", m.ID, err)
+		}
+	}
+}
+
+func init() {
+	RegisterSessionData("utils.paging", &PagingHandlerCTX{})
+	RegisterHandler("utils.pageDeeper", pageDeeper)
+	RegisterHandler("utils.pageUp", pageUp)
+	RegisterHandler("utils.nextPage", nextPage)
+	RegisterHandler("utils.pageBack", pageBack)
+	RegisterHandler("utils.closeEmbed", closeEmbed)
 }
 
 // PagingHandlerCTX is the context object for a paging embed
@@ -248,6 +525,54 @@ type PagingHandlerCTX struct {
 	parentPage  *PagingHandlerCTX
 }
 
+// pagingState is the serializable form of a PagingHandlerCTX
+type pagingState struct {
+	Fields      []*PagingListField      `json:"fields"`
+	BaseEmbed   *discordgo.MessageEmbed `json:"base_embed"`
+	CurrentPage int                     `json:"current_page"`
+	Parent      *pagingState            `json:"parent,omitempty"`
+}
+
+func (ctx *PagingHandlerCTX) state() *pagingState {
+	if ctx == nil {
+		return nil
+	}
+	return &pagingState{
+		Fields:      ctx.Fields,
+		BaseEmbed:   ctx.BaseEmbed,
+		CurrentPage: ctx.currentPage,
+		Parent:      ctx.parentPage.state(),
+	}
+}
+
+func (p *pagingState) context() *PagingHandlerCTX {
+	if p == nil {
+		return nil
+	}
+	return &PagingHandlerCTX{
+		Fields:      p.Fields,
+		BaseEmbed:   p.BaseEmbed,
+		currentPage: p.CurrentPage,
+		parentPage:  p.Parent.context(),
+	}
+}
+
+// MarshalJSON encodes the paging context including the current page and the pages above it, so it can be saved
+func (ctx *PagingHandlerCTX) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ctx.state())
+}
+
+// UnmarshalJSON decodes a paging context encoded by MarshalJSON
+func (ctx *PagingHandlerCTX) UnmarshalJSON(data []byte) error {
+	var p pagingState
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return err
+	}
+	*ctx = *p.context()
+	return nil
+}
+
 // PagingPage is an object describing what a page deeper will contain
 type PagingPage struct {
 	Emoji       *discordgo.Emoji
@@ -295,32 +620,32 @@ func PagingEmbedHandler(s *EmbedSession, ctx *PagingHandlerCTX) {
 	em.Fields = nil
 
 	if ctx.currentPage != 1 {
-		em.AddField(
+		em.AddNamedField(
 			"Back",
 			"Goes back a page.",
 			false,
 			&discordgo.Emoji{Name: "⬅"},
-			pageBack,
+			"utils.pageBack",
 		)
 	}
 
 	if ctx.parentPage != nil {
-		em.AddField(
+		em.AddNamedField(
 			"Up",
 			"Goes back a menu",
 			false,
 			&discordgo.Emoji{Name: "🔼"},
-			pageUp,
+			"utils.pageUp",
 		)
 	}
 
 	if ctx.currentPage != pages {
-		em.AddField(
+		em.AddNamedField(
 			"Forward",
 			"Goes forward a page.",
 			false,
 			&discordgo.Emoji{Name: "➡"},
-			nextPage,
+			"utils.nextPage",
 		)
 	}
 
@@ -334,22 +659,22 @@ func PagingEmbedHandler(s *EmbedSession, ctx *PagingHandlerCTX) {
 				nil,
 			)
 		} else {
-			em.AddField(
+			em.AddNamedField(
 				f.Name,
 				f.Value,
 				f.Inline,
 				f.PageLink.Emoji,
-				pageDeeper,
+				"utils.pageDeeper",
 			)
 		}
 	}
 
-	em.AddField(
+	em.AddNamedField(
 		"Close",
 		"Closes the embed.",
 		false,
 		&discordgo.Emoji{Name: "❌"},
-		closeEmbed,
+		"utils.closeEmbed",
 	)
 
 	if s.message != nil {
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/auttaja/discordgo"
+)
+
+// testSession adds a session on the message with an option for the emoji that calls handler
+func testSession(messageID string, emoji *discordgo.Emoji, handler func(*EmbedSession, *discordgo.MessageReactionAdd)) *EmbedSession {
+	s := NewEmbedSession(nil, &discordgo.User{ID: "1"}, nil)
+	s.OnExpire = 0
+	s.message = &discordgo.Message{ID: messageID}
+	// there is no discord session to remove reactions with
+	s.cannotManage = 1
+
+	em := NewStatefulEmbed(s)
+	em.AddReaction(emoji, handler)
+	s.currentState = em
+
+	sessionsHolder.locker.Lock()
+	sessionsHolder.sessions[messageID] = s
+	sessionsHolder.locker.Unlock()
+	return s
+}
+
+func testReaction(messageID string) *discordgo.MessageReactionAdd {
+	return &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+		MessageID: messageID,
+		UserID:    "1",
+	}}
+}
+
+func TestConcurrentReactionsOnOneSession(t *testing.T) {
+	emoji := &discordgo.Emoji{Name: "a"}
+
+	var calls int
+	var running, maxRunning int32
+	testSession("race-1", emoji, func(*EmbedSession, *discordgo.MessageReactionAdd) {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, n)
+		}
+		calls++
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handleReaction(nil, testReaction("race-1"), emoji.APIName())
+		}()
+	}
+	wg.Wait()
+
+	if calls != 50 {
+		t.Fatalf("handler was called %d times instead of 50", calls)
+	}
+	if maxRunning != 1 {
+		t.Fatalf("%d handlers of the same session ran at the same time", maxRunning)
+	}
+}
+
+func TestSlowSessionDoesNotBlockOthers(t *testing.T) {
+	emoji := &discordgo.Emoji{Name: "b"}
+
+	release := make(chan struct{})
+	testSession("race-slow", emoji, func(*EmbedSession, *discordgo.MessageReactionAdd) {
+		<-release
+	})
+
+	done := make(chan struct{})
+	testSession("race-fast", emoji, func(*EmbedSession, *discordgo.MessageReactionAdd) {
+		close(done)
+	})
+
+	go handleReaction(nil, testReaction("race-slow"), emoji.APIName())
+	go handleReaction(nil, testReaction("race-fast"), emoji.APIName())
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a slow session blocked the handler of another session")
+	}
+	close(release)
+}
+
+func TestHandlerCanTouchHolder(t *testing.T) {
+	emoji := &discordgo.Emoji{Name: "c"}
+
+	testSession("race-expire", emoji, func(s *EmbedSession, _ *discordgo.MessageReactionAdd) {
+		s.Expire()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		handleReaction(nil, testReaction("race-expire"), emoji.APIName())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a handler expiring its own session deadlocked")
+	}
+
+	sessionsHolder.locker.Lock()
+	_, ok := sessionsHolder.sessions["race-expire"]
+	sessionsHolder.locker.Unlock()
+	if ok {
+		t.Fatal("expired session is still in the holder")
+	}
+}
+
+func TestConcurrentReactionsAndJanitor(t *testing.T) {
+	emoji := &discordgo.Emoji{Name: "d"}
+	s := testSession("race-janitor", emoji, func(*EmbedSession, *discordgo.MessageReactionAdd) {})
+	s.created = time.Now()
+	s.lastUsed = s.created
+	s.IdleTimeout = time.Hour
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			handleReaction(nil, testReaction("race-janitor"), emoji.APIName())
+		}()
+		go func() {
+			defer wg.Done()
+			expiredSessions(time.Now())
+		}()
+	}
+	wg.Wait()
+
+	if len(expiredSessions(time.Now().Add(2*time.Hour))) != 1 {
+		t.Fatal("session did not expire after its idle timeout")
+	}
+}
+
+func TestOptionAccess(t *testing.T) {
+	vote := &discordgo.Emoji{Name: "e"}
+	closeEmoji := &discordgo.Emoji{Name: "f"}
+
+	var votes, closes int
+	s := testSession("access", vote, func(*EmbedSession, *discordgo.MessageReactionAdd) { votes++ })
+	s.currentState.AddReaction(closeEmoji, func(*EmbedSession, *discordgo.MessageReactionAdd) { closes++ })
+	s.currentState.SetAccess(vote, AllowEveryone())
+
+	other := testReaction("access")
+	other.UserID = "2"
+
+	handleReaction(nil, other, vote.APIName())
+	handleReaction(nil, other, closeEmoji.APIName())
+	handleReaction(nil, testReaction("access"), closeEmoji.APIName())
+
+	if votes != 1 || closes != 1 {
+		t.Fatalf("expected 1 vote and 1 close, got %d votes and %d closes", votes, closes)
+	}
+
+	s.SetAccess(AllowUsers("2"))
+	handleReaction(nil, other, closeEmoji.APIName())
+	handleReaction(nil, testReaction("access"), closeEmoji.APIName())
+
+	if closes != 2 {
+		t.Fatalf("expected 2 closes after allowing user 2, got %d", closes)
+	}
+}
+
+func TestToggles(t *testing.T) {
+	emoji := &discordgo.Emoji{Name: "g"}
+
+	var on, off int
+	s := testSession("toggle", &discordgo.Emoji{Name: "h"}, nil)
+	s.SetAccess(AllowEveryone())
+	s.currentState.AddToggle("Subscribe", "Get notified", false, emoji,
+		func(*EmbedSession, *discordgo.MessageReactionAdd) { on++ },
+		func(*EmbedSession, *discordgo.MessageReactionRemove) { off++ },
+	)
+
+	other := testReaction("toggle")
+	other.UserID = "2"
+	handleReaction(nil, testReaction("toggle"), emoji.APIName())
+	handleReaction(nil, other, emoji.APIName())
+	handleReactionRemove(nil, &discordgo.MessageReactionRemove{MessageReaction: other.MessageReaction}, emoji.APIName())
+
+	if on != 2 || off != 1 {
+		t.Fatalf("expected 2 on and 1 off, got %d on and %d off", on, off)
+	}
+	if !s.Toggled(emoji, "1") || s.Toggled(emoji, "2") {
+		t.Fatal("toggle state doesn't match the reactions")
+	}
+	if users := s.ToggledBy(emoji); len(users) != 1 || users[0] != "1" {
+		t.Fatalf("expected only user 1 to have the toggle on, got %v", users)
+	}
+}
+
+func TestMissingSessionsIsBounded(t *testing.T) {
+	m := newMissingSet(2)
+	m.add("1")
+	m.add("2")
+	m.add("3")
+	if m.has("1") || !m.has("2") || !m.has("3") || len(m.ids) != 2 {
+		t.Fatal("the oldest message was not forgotten")
+	}
+
+	m.remove("2")
+	if m.has("2") {
+		t.Fatal("a saved session is still remembered as missing")
+	}
+}
+
+// endRecorder reports the sessions that ended
+type endRecorder chan string
+
+func (e endRecorder) sessionEnded(s *EmbedSession) {
+	e <- s.message.ID
+}
+
+func TestJanitorSkipsBusySessions(t *testing.T) {
+	ended := make(endRecorder, 2)
+	for _, id := range []string{"janitor-busy", "janitor-idle"} {
+		s := testSession(id, &discordgo.Emoji{Name: "g"}, func(*EmbedSession, *discordgo.MessageReactionAdd) {})
+		s.CtxData = ended
+		s.created = time.Now().Add(-2 * time.Hour)
+		s.Timeout = time.Hour
+		if id == "janitor-busy" {
+			// a handler of this session is stuck
+			s.handlerMu.Lock()
+			defer s.handlerMu.Unlock()
+		}
+	}
+
+	stop := StartJanitor(time.Millisecond)
+	defer stop()
+
+	select {
+	case id := <-ended:
+		if id != "janitor-idle" {
+			t.Fatalf("expected the idle session to expire, got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a busy session kept the janitor from expiring the others")
+	}
+}
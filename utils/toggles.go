@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/auttaja/discordgo"
+)
+
+// OnRemove sets the handler that is called when a user removes their reaction from the options with the emoji.
+// Options with a remove handler keep the reactions of users, so they can be removed again
+func (s *StatefulEmbed) OnRemove(emoji *discordgo.Emoji, handler func(*EmbedSession, *discordgo.MessageReactionRemove)) *StatefulEmbed {
+	for _, o := range s.options {
+		if o.Emoji.ApiName == emoji.APIName() {
+			o.RemoveHandler = handler
+		}
+	}
+	return s
+}
+
+// OnNamedRemove is like OnRemove, but takes the name of a handler registered with RegisterRemoveHandler
+func (s *StatefulEmbed) OnNamedRemove(emoji *discordgo.Emoji, handlerName string) *StatefulEmbed {
+	for _, o := range s.options {
+		if o.Emoji.ApiName == emoji.APIName() {
+			o.removeHandlerName = handlerName
+		}
+	}
+	return s
+}
+
+// AddToggle adds a field with an emoji that works like a checkbox: reacting turns it on for the user
+// and removing the reaction turns it off again. on and off are called when that happens and may be nil,
+// use Toggled and ToggledBy to read the state
+// name   : the field name
+// value  : the field value
+// inline : determines if the field should be placed inline or not
+// emoji  : the emoji to react with
+// on     : the handler to call when a user turns the toggle on
+// off    : the handler to call when a user turns the toggle off
+func (s *StatefulEmbed) AddToggle(name, value string, inline bool, emoji *discordgo.Emoji, on func(*EmbedSession, *discordgo.MessageReactionAdd), off func(*EmbedSession, *discordgo.MessageReactionRemove)) {
+	s.addOption(name, value, emoji, on, "")
+	o := s.options[len(s.options)-1]
+	o.RemoveHandler = off
+	o.toggle = true
+	s.MessageEmbed = s.MessageEmbed.AddField(fmt.Sprintf("%s %s", emoji, name), value, inline)
+}
+
+// AddNamedToggle is like AddToggle, but takes the names of handlers registered with RegisterHandler
+// and RegisterRemoveHandler. Either name may be empty
+func (s *StatefulEmbed) AddNamedToggle(name, value string, inline bool, emoji *discordgo.Emoji, onName, offName string) {
+	s.addOption(name, value, emoji, nil, onName)
+	o := s.options[len(s.options)-1]
+	o.removeHandlerName = offName
+	o.toggle = true
+	s.MessageEmbed = s.MessageEmbed.AddField(fmt.Sprintf("%s %s", emoji, name), value, inline)
+}
+
+// Toggled returns true if the user has the toggle with the emoji turned on
+func (s *EmbedSession) Toggled(emoji *discordgo.Emoji, userID string) bool {
+	s.toggleMu.Lock()
+	defer s.toggleMu.Unlock()
+	return s.toggled[emoji.APIName()][userID]
+}
+
+// ToggledBy returns the IDs of the users that have the toggle with the emoji turned on, sorted
+func (s *EmbedSession) ToggledBy(emoji *discordgo.Emoji) []string {
+	s.toggleMu.Lock()
+	defer s.toggleMu.Unlock()
+	return sortedKeys(s.toggled[emoji.APIName()])
+}
+
+func (s *EmbedSession) setToggled(emoji, userID string, on bool) {
+	s.toggleMu.Lock()
+	defer s.toggleMu.Unlock()
+
+	if !on {
+		delete(s.toggled[emoji], userID)
+		return
+	}
+
+	if s.toggled == nil {
+		s.toggled = make(map[string]map[string]bool)
+	}
+	if s.toggled[emoji] == nil {
+		s.toggled[emoji] = make(map[string]bool)
+	}
+	s.toggled[emoji][userID] = true
+}
+
+// toggledState returns the users that have each toggle on, for saving the session
+func (s *EmbedSession) toggledState() map[string][]string {
+	s.toggleMu.Lock()
+	defer s.toggleMu.Unlock()
+
+	if len(s.toggled) == 0 {
+		return nil
+	}
+	state := make(map[string][]string, len(s.toggled))
+	for emoji, users := range s.toggled {
+		if len(users) > 0 {
+			state[emoji] = sortedKeys(users)
+		}
+	}
+	return state
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,38 +1,124 @@
 package dgframework
 
 import (
-	"reflect"
+	"context"
+	"errors"
+	"sync"
+	"time"
 
+	"github.com/auttaja/dgframework/events"
 	"github.com/auttaja/discordgo"
 )
 
+// ErrWaiterCancelled is returned by Waiter.Err when the waiter was cancelled with Cancel, or the bot closed
+var ErrWaiterCancelled = errors.New("waiter cancelled")
+
 // CheckFunc represents a generic check function to wait for
 type CheckFunc func(interface{}) bool
 
-// Waiter represents a single event you'd like to wait for
+// Waiter represents a single event you'd like to wait for.
+// Response receives the event once the check passes and is closed right after. It is also closed,
+// without receiving anything, when the waiter is cancelled or times out, in which case Err tells why
 type Waiter struct {
 	checker    CheckFunc
 	Response   chan interface{}
-	eventType  reflect.Type
 	removeFunc func()
+
+	mu   sync.Mutex
+	done chan struct{}
+	err  error
 }
 
-// WaitFor submits a request to wait for an event.  You are responsible for listening to the channel to get the data
+// WaitFor submits a request to wait for an event.  You are responsible for listening to the channel to get the data.
+// The waiter keeps waiting until the event arrives or Cancel is called
 func WaitFor(s *discordgo.Session, event interface{}, checkFunc CheckFunc) *Waiter {
+	return WaitForContext(context.Background(), s, event, checkFunc)
+}
+
+// WaitForTimeout is like WaitFor, but stops waiting after the timeout. Err returns context.DeadlineExceeded when it does
+func WaitForTimeout(s *discordgo.Session, event interface{}, checkFunc CheckFunc, timeout time.Duration) *Waiter {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	w := WaitForContext(ctx, s, event, checkFunc)
+	go func() {
+		<-w.done
+		cancel()
+	}()
+	return w
+}
+
+// WaitForContext is like WaitFor, but stops waiting when the context is done. Err returns the error of the context when it does
+func WaitForContext(ctx context.Context, s *discordgo.Session, event interface{}, checkFunc CheckFunc) *Waiter {
+	return WaitForIn(ctx, s, event, "", "", checkFunc)
+}
+
+// WaitForIn is like WaitForContext, but only checks events in the channel and from the user, an empty ID meaning any.
+// Only message, reaction and typing events have a channel and user, so use empty IDs for other events
+func WaitForIn(ctx context.Context, s *discordgo.Session, event interface{}, channelID, userID string, checkFunc CheckFunc) *Waiter {
 	w := new(Waiter)
-	w.eventType = reflect.TypeOf(event)
 	w.Response = make(chan interface{}, 1)
 	w.checker = checkFunc
-	w.removeFunc = s.AddHandler(w.waitCallback)
+	w.done = make(chan struct{})
+
+	// the listener can't finish the waiter before removeFunc is set, as it needs the lock
+	d := events.For(s)
+	w.mu.Lock()
+	w.removeFunc = d.Add(event, events.Filter{ChannelID: channelID, UserID: userID}, w.waitCallback)
+	w.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.finish(nil, ctx.Err())
+		case <-d.Done():
+			w.finish(nil, ErrWaiterCancelled)
+		case <-w.done:
+		}
+	}()
 
 	return w
 }
 
-func (w *Waiter) waitCallback(s *discordgo.Session, event interface{}) {
-	if reflect.TypeOf(event).Elem() == w.eventType {
-		if w.checker(event) {
-			w.removeFunc()
-			w.Response <- event
-		}
+// Cancel stops waiting and closes Response, it does nothing if the waiter already finished
+func (w *Waiter) Cancel() {
+	w.finish(nil, ErrWaiterCancelled)
+}
+
+// Err returns why the waiter stopped waiting without receiving the event: ErrWaiterCancelled, or the error
+// of the context or timeout. It returns nil while the waiter is still waiting and when the event was received
+func (w *Waiter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Done returns a channel that is closed when the waiter stops waiting, for whatever reason
+func (w *Waiter) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *Waiter) waitCallback(event interface{}) {
+	if w.checker(event) {
+		w.finish(event, nil)
+	}
+}
+
+// finish removes the handler and closes Response, sending the event first if there is one.
+// Only the first call does anything
+func (w *Waiter) finish(event interface{}, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	w.removeFunc()
+	w.err = err
+	if event != nil {
+		w.Response <- event
 	}
+	close(w.Response)
+	close(w.done)
 }
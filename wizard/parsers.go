@@ -0,0 +1,72 @@
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+// Text returns a Parse function accepting any text up to maxLength characters, 0 meaning no maximum
+func Text(maxLength int) func(*router.Context, *discordgo.Message) (interface{}, error) {
+	return func(_ *router.Context, m *discordgo.Message) (interface{}, error) {
+		if maxLength > 0 && utf8.RuneCountInString(m.Content) > maxLength {
+			return nil, fmt.Errorf("please keep it under %d characters", maxLength)
+		}
+		return m.Content, nil
+	}
+}
+
+// Integer returns a Parse function accepting whole numbers between min and max, the answer is an int
+func Integer(min, max int) func(*router.Context, *discordgo.Message) (interface{}, error) {
+	return func(_ *router.Context, m *discordgo.Message) (interface{}, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(m.Content))
+		if err != nil || n < min || n > max {
+			return nil, fmt.Errorf("please reply with a number from %d to %d", min, max)
+		}
+		return n, nil
+	}
+}
+
+// Choice returns a Parse function accepting one of the options, ignoring case. The answer is the option as given
+func Choice(options ...string) func(*router.Context, *discordgo.Message) (interface{}, error) {
+	return func(_ *router.Context, m *discordgo.Message) (interface{}, error) {
+		reply := strings.TrimSpace(m.Content)
+		for _, o := range options {
+			if strings.EqualFold(o, reply) {
+				return o, nil
+			}
+		}
+		return nil, fmt.Errorf("please reply with one of: %s", strings.Join(options, ", "))
+	}
+}
+
+// Channel is a Parse function accepting a mention, ID or name of a channel in the guild, the answer is a *discordgo.Channel
+func Channel(ctx *router.Context, m *discordgo.Message) (interface{}, error) {
+	return resolved(ctx.ResolveChannel(m.Content))
+}
+
+// Role is a Parse function accepting a mention, ID or name of a role in the guild, the answer is a *discordgo.Role
+func Role(ctx *router.Context, m *discordgo.Message) (interface{}, error) {
+	return resolved(ctx.ResolveRole(m.Content))
+}
+
+// Member is a Parse function accepting a mention, ID or name of a member of the guild, the answer is a *discordgo.Member
+func Member(ctx *router.Context, m *discordgo.Message) (interface{}, error) {
+	return resolved(ctx.ResolveMember(m.Content))
+}
+
+// resolved turns the result of a resolver into an answer, or an error that explains what went wrong
+func resolved(v interface{}, err error) (interface{}, error) {
+	if ambiguous, ok := err.(*router.AmbiguousError); ok {
+		return nil, fmt.Errorf("that matches more than one, please be more specific: %s", strings.Join(ambiguous.Candidates, ", "))
+	}
+	if err != nil {
+		return nil, errors.New("could not find that in this server, please try again")
+	}
+	return v, nil
+}
@@ -0,0 +1,248 @@
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+// End can be returned by Step.Next to finish the wizard after the step
+const End = "\x00end"
+
+var (
+	// ErrCancelled gets returned when the user cancelled the wizard
+	ErrCancelled = errors.New("the wizard has been cancelled")
+
+	// ErrAlreadyRunning gets returned when the user already has a wizard running in the channel
+	ErrAlreadyRunning = errors.New("a wizard is already running for this user in this channel")
+
+	// ErrUnknownStep gets returned when Step.Next returns the name of a step that doesn't exist
+	ErrUnknownStep = errors.New("the wizard has no step with that name")
+
+	running = &runningWizards{wizards: make(map[string]bool)}
+)
+
+// Answers are the answers given to the steps of a wizard, by step name
+type Answers map[string]interface{}
+
+// Step is a single question of a Wizard
+type Step struct {
+	// Name is the key of the answer in the Answers
+	Name string
+
+	// Prompt is the question that gets asked
+	Prompt string
+
+	// Parse validates and converts the reply to the question. When it returns an error, the error is shown
+	// with its first letter capitalized and the question is asked again. When nil the content of the reply is the answer
+	Parse func(ctx *router.Context, m *discordgo.Message) (interface{}, error)
+
+	// Next returns the name of the step that follows, based on the answers so far. When nil or when it returns
+	// an empty string the next step in the list follows, when it returns End the wizard finishes
+	Next func(answers Answers) string
+
+	// Format formats the answer for the summary, when nil answers that can be mentioned are mentioned
+	// and all others are formatted using fmt
+	Format func(answer interface{}) string
+
+	// Timeout is how long to wait for a reply, overriding the Timeout of the Wizard when not 0
+	Timeout time.Duration
+}
+
+// Wizard asks a sequence of questions, the steps, and collects the answers.
+// The user can go back to the previous question and cancel the wizard at any time using the keywords
+type Wizard struct {
+	Title string
+	Steps []*Step
+
+	// Timeout is how long to wait for a reply to each step
+	Timeout time.Duration
+
+	// BackKeyword and CancelKeyword are the replies that go back a step and cancel the wizard
+	BackKeyword   string
+	CancelKeyword string
+
+	// Summary makes the wizard show all the answers and ask for confirmation before finishing
+	Summary bool
+}
+
+// New returns a new Wizard with the default timeout and keywords
+func New(title string, steps ...*Step) *Wizard {
+	return &Wizard{
+		Title:         title,
+		Steps:         steps,
+		Timeout:       2 * time.Minute,
+		BackKeyword:   "back",
+		CancelKeyword: "cancel",
+		Summary:       true,
+	}
+}
+
+type runningWizards struct {
+	sync.Mutex
+	wizards map[string]bool
+}
+
+// start marks a wizard as running for the user in the channel, it returns false if one already was
+func (r *runningWizards) start(key string) bool {
+	r.Lock()
+	defer r.Unlock()
+	if r.wizards[key] {
+		return false
+	}
+	r.wizards[key] = true
+	return true
+}
+
+func (r *runningWizards) stop(key string) {
+	r.Lock()
+	delete(r.wizards, key)
+	r.Unlock()
+}
+
+// Run runs the wizard for the author of the context in its channel and returns the answers.
+// It returns ErrCancelled when the user cancelled, ErrAlreadyRunning when the user already has
+// a wizard running in the channel and a router.TimeoutError when the user didn't reply in time
+func (w *Wizard) Run(ctx *router.Context) (Answers, error) {
+	key := ctx.Msg.ChannelID + ":" + ctx.Msg.Author.ID
+	if !running.start(key) {
+		_, _ = ctx.Reply("Please finish or cancel the wizard you already started here first")
+		return nil, ErrAlreadyRunning
+	}
+	defer running.stop(key)
+
+	answers := Answers{}
+	var history []int
+
+	if w.Title != "" {
+		_, err := ctx.Reply(fmt.Sprintf("**%s**\nReply `%s` to go back a question, or `%s` to stop.", w.Title, w.BackKeyword, w.CancelKeyword))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < len(w.Steps); {
+		step := w.Steps[i]
+
+		timeout := w.Timeout
+		if step.Timeout != 0 {
+			timeout = step.Timeout
+		}
+
+		m, err := ctx.Prompt(step.Prompt, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		switch reply := strings.TrimSpace(m.Content); {
+		case strings.EqualFold(reply, w.CancelKeyword):
+			_, _ = ctx.Reply("Cancelled")
+			return nil, ErrCancelled
+		case strings.EqualFold(reply, w.BackKeyword):
+			if len(history) == 0 {
+				_, _ = ctx.Reply("This is the first question")
+				continue
+			}
+			i = history[len(history)-1]
+			history = history[:len(history)-1]
+			delete(answers, w.Steps[i].Name)
+			continue
+		}
+
+		var answer interface{} = m.Content
+		if step.Parse != nil {
+			answer, err = step.Parse(ctx, m)
+			if err != nil {
+				_, err = ctx.Reply(sentence(err.Error()))
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		answers[step.Name] = answer
+		history = append(history, i)
+
+		i, err = w.next(i, answers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !w.Summary {
+		return answers, nil
+	}
+
+	ok, err := ctx.ConfirmTimeout(w.summary(history, answers), w.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		_, _ = ctx.Reply("Cancelled")
+		return nil, ErrCancelled
+	}
+	return answers, nil
+}
+
+// next returns the index of the step following step i, or len(w.Steps) if the wizard is finished
+func (w *Wizard) next(i int, answers Answers) (int, error) {
+	step := w.Steps[i]
+	if step.Next == nil {
+		return i + 1, nil
+	}
+
+	name := step.Next(answers)
+	switch name {
+	case "":
+		return i + 1, nil
+	case End:
+		return len(w.Steps), nil
+	}
+
+	for j, s := range w.Steps {
+		if s.Name == name {
+			return j, nil
+		}
+	}
+	return 0, ErrUnknownStep
+}
+
+// summary lists the answers of the steps that were answered, in the order they were asked
+func (w *Wizard) summary(history []int, answers Answers) string {
+	lines := []string{"Is this correct?"}
+	if w.Title != "" {
+		lines[0] = fmt.Sprintf("**%s**\n%s", w.Title, lines[0])
+	}
+
+	for _, i := range history {
+		step := w.Steps[i]
+		var value string
+		switch answer := answers[step.Name].(type) {
+		case interface{ Mention() string }:
+			value = answer.Mention()
+		default:
+			value = fmt.Sprint(answer)
+		}
+		if step.Format != nil {
+			value = step.Format(answers[step.Name])
+		}
+		lines = append(lines, fmt.Sprintf("**%s**: %s", step.Name, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sentence capitalizes the first letter of an error message, so it can be shown to the user
+func sentence(message string) string {
+	r, size := utf8.DecodeRuneInString(message)
+	if size == 0 {
+		return message
+	}
+	return string(unicode.ToUpper(r)) + message[size:]
+}
@@ -0,0 +1,235 @@
+package wizard
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/auttaja/dgframework/events"
+	"github.com/auttaja/dgframework/router"
+	"github.com/auttaja/discordgo"
+)
+
+// fakeDiscord answers every request to the REST API, passing the content of sent messages to the channel
+type fakeDiscord chan string
+
+func (f fakeDiscord) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := "{}"
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/messages") {
+		var data struct {
+			Content string `json:"content"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&data)
+		f <- data.Content
+		body = `{"id":"100","channel_id":"3","author":{"id":"5"}}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+type result struct {
+	answers Answers
+	err     error
+}
+
+// wizardTest runs a wizard and plays the user replying to it
+type wizardTest struct {
+	t      *testing.T
+	s      *discordgo.Session
+	sent   fakeDiscord
+	result chan result
+}
+
+func runWizard(t *testing.T, w *Wizard) *wizardTest {
+	sent := make(fakeDiscord, 10)
+	s := &discordgo.Session{
+		State:        discordgo.NewState(),
+		StateEnabled: true,
+		Ratelimiter:  discordgo.NewRatelimiter(),
+		Client:       &http.Client{Transport: sent},
+	}
+	ctx := &router.Context{
+		Ses:     s,
+		Channel: &discordgo.Channel{ID: "3", Session: s},
+		Msg:     &discordgo.Message{ChannelID: "3", Author: &discordgo.User{ID: "1"}},
+	}
+
+	test := &wizardTest{t: t, s: s, sent: sent, result: make(chan result, 1)}
+	go func() {
+		answers, err := w.Run(ctx)
+		test.result <- result{answers, err}
+	}()
+	return test
+}
+
+// expect fails the test unless the next message the wizard sends starts with prefix
+func (w *wizardTest) expect(prefix string) string {
+	w.t.Helper()
+	select {
+	case content := <-w.sent:
+		if !strings.HasPrefix(content, prefix) {
+			w.t.Fatalf("expected a message starting with %q, got %q", prefix, content)
+		}
+		return content
+	case <-time.After(time.Second):
+		w.t.Fatalf("expected a message starting with %q, got nothing", prefix)
+	}
+	return ""
+}
+
+// send feeds the event to the wizard once it is listening
+func (w *wizardTest) send(event interface{}) {
+	w.t.Helper()
+	d := events.For(w.s)
+	for start := time.Now(); d.Len() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			w.t.Fatal("the wizard isn't waiting for a reply")
+		}
+	}
+	d.Handle(w.s, event)
+}
+
+func (w *wizardTest) reply(content string) {
+	w.t.Helper()
+	w.send(&discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: "3",
+		Author:    &discordgo.User{ID: "1"},
+		Content:   content,
+	}})
+}
+
+func (w *wizardTest) react(emoji string) {
+	w.t.Helper()
+	w.send(&discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+		MessageID: "100",
+		ChannelID: "3",
+		UserID:    "1",
+		Emoji:     &discordgo.Emoji{Name: emoji},
+	}})
+}
+
+func (w *wizardTest) wait() result {
+	w.t.Helper()
+	select {
+	case r := <-w.result:
+		return r
+	case <-time.After(time.Second):
+		w.t.Fatal("the wizard didn't finish")
+	}
+	return result{}
+}
+
+func TestWizardRun(t *testing.T) {
+	w := New("Signup",
+		&Step{Name: "name", Prompt: "Name?", Parse: Text(5)},
+		&Step{Name: "age", Prompt: "Age?", Parse: Integer(1, 120)},
+	)
+	test := runWizard(t, w)
+
+	test.expect("**Signup**")
+	test.expect("Name?")
+	test.reply("back")
+	test.expect("This is the first question")
+	test.expect("Name?")
+	// the limit is in characters, not bytes
+	test.reply("éééééé")
+	test.expect("Please keep it under 5 characters")
+	test.expect("Name?")
+	test.reply("ééééé")
+	test.expect("Age?")
+	test.reply("back")
+	test.expect("Name?")
+	test.reply("Bob")
+	test.expect("Age?")
+	test.reply("old")
+	test.expect("Please reply with a number from 1 to 120")
+	test.expect("Age?")
+	test.reply("30")
+
+	summary := test.expect("**Signup**\nIs this correct?")
+	if !strings.Contains(summary, "**name**: Bob\n**age**: 30") {
+		t.Errorf("expected the summary to list the answers in order, got %q", summary)
+	}
+	test.react("✅")
+
+	r := test.wait()
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.answers["name"] != "Bob" || r.answers["age"] != 30 {
+		t.Fatalf("expected name Bob and age 30, got %v", r.answers)
+	}
+}
+
+func TestWizardCancel(t *testing.T) {
+	w := New("", &Step{Name: "name", Prompt: "Name?"})
+	test := runWizard(t, w)
+	test.expect("Name?")
+	test.reply("CANCEL")
+	test.expect("Cancelled")
+	if r := test.wait(); r.err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %v", r.err)
+	}
+
+	// declining the summary cancels too
+	test = runWizard(t, w)
+	test.expect("Name?")
+	test.reply("Bob")
+	test.expect("Is this correct?")
+	test.react("❌")
+	test.expect("Cancelled")
+	if r := test.wait(); r.err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled after declining the summary, got %v", r.err)
+	}
+}
+
+func TestWizardBranching(t *testing.T) {
+	w := New("",
+		&Step{Name: "pet", Prompt: "Cat or dog?", Parse: Choice("cat", "dog"), Next: func(answers Answers) string {
+			if answers["pet"] == "dog" {
+				return "walks"
+			}
+			return ""
+		}},
+		&Step{Name: "indoor", Prompt: "Indoor?", Next: func(Answers) string { return End }},
+		&Step{Name: "walks", Prompt: "Walks a day?"},
+	)
+	w.Summary = false
+	test := runWizard(t, w)
+
+	test.expect("Cat or dog?")
+	test.reply("Dog")
+	test.expect("Walks a day?")
+	// going back returns to the step that was asked, not the one before it in the list
+	test.reply("back")
+	test.expect("Cat or dog?")
+	test.reply("cat")
+	test.expect("Indoor?")
+	test.reply("yes")
+
+	r := test.wait()
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if len(r.answers) != 2 || r.answers["pet"] != "cat" || r.answers["indoor"] != "yes" {
+		t.Fatalf("expected the cat branch to be answered, got %v", r.answers)
+	}
+}
+
+func TestWizardTimeout(t *testing.T) {
+	w := New("", &Step{Name: "name", Prompt: "Name?"}, &Step{Name: "age", Prompt: "Age?", Timeout: 10 * time.Millisecond})
+	test := runWizard(t, w)
+
+	test.expect("Name?")
+	test.reply("Bob")
+	test.expect("Age?")
+	if r := test.wait(); !router.IsTimeout(r.err) {
+		t.Fatalf("expected a TimeoutError, got %v", r.err)
+	}
+}